@@ -12,8 +12,8 @@ const (
 )
 
 // 备份配置
-func backups(fileName string, confMap map[string]interface{}) error {
-	fileName, err := getFullBackFileName(fileName)
+func (cl *Client) backups(fileName string, confMap map[string]interface{}) error {
+	fileName, err := cl.getFullBackFileName(fileName)
 	if err != nil {
 		return err
 	}
@@ -34,8 +34,8 @@ func backups(fileName string, confMap map[string]interface{}) error {
 }
 
 // 备份恢复
-func backupRecovery(fileName string) (map[string]interface{}, error) {
-	fileName, err := getFullBackFileName(fileName)
+func (cl *Client) backupRecovery(fileName string) (map[string]interface{}, error) {
+	fileName, err := cl.getFullBackFileName(fileName)
 	if err != nil {
 		return nil, err
 	}
@@ -53,12 +53,12 @@ func backupRecovery(fileName string) (map[string]interface{}, error) {
 }
 
 // 本地备份文件全名
-func getFullBackFileName(fileName string) (string, error) {
-	dir := e.confDir + backupsDir
+func (cl *Client) getFullBackFileName(fileName string) (string, error) {
+	dir := cl.env.confDir + backupsDir
 	dirInfo, err := os.Stat(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			err = os.MkdirAll(dir, 0664)
+			err = os.MkdirAll(dir, 0775)
 			if err != nil {
 				return "", errors.New("备份目录创建失败..." + err.Error())
 			}
@@ -0,0 +1,75 @@
+package conf
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ChangeSet 配置变更内容，配置键均按字典序排列
+type ChangeSet struct {
+	// Old 变更前的配置对象，首次加载时为nil
+	Old *ConfigObject
+	// New 变更后的配置对象
+	New *ConfigObject
+	// Added 新增的配置键
+	Added []string
+	// Removed 删除的配置键
+	Removed []string
+	// Modified 配置值有变化的配置键
+	Modified []string
+}
+
+// ChangeHandler 当配置有变更时以变更内容调用此方法，配置内容没有变化时不会调用
+type ChangeHandler interface {
+	ChangeHandel(fileName string, cs *ChangeSet)
+}
+
+// Empty 判断是否没有任何配置键变化
+func (cs *ChangeSet) Empty() bool {
+	return len(cs.Added) == 0 && len(cs.Removed) == 0 && len(cs.Modified) == 0
+}
+
+// Changed 判断指定配置键是否有变化(新增、删除或者修改)
+func (cs *ChangeSet) Changed(key string) bool {
+	for _, keys := range [][]string{cs.Added, cs.Removed, cs.Modified} {
+		i := sort.SearchStrings(keys, key)
+		if i < len(keys) && keys[i] == key {
+			return true
+		}
+	}
+	return false
+}
+
+// 比对新旧配置对象，old 为nil时全部配置键视为新增
+func diff(old *ConfigObject, new *ConfigObject) *ChangeSet {
+	cs := &ChangeSet{
+		Old:      old,
+		New:      new,
+		Added:    make([]string, 0),
+		Removed:  make([]string, 0),
+		Modified: make([]string, 0),
+	}
+	var oldData map[string]Result
+	if old != nil {
+		oldData = old.data
+	}
+	for k, v := range new.data {
+		o, ok := oldData[k]
+		if !ok {
+			cs.Added = append(cs.Added, k)
+			continue
+		}
+		if o.dataType != v.dataType || !reflect.DeepEqual(o.value, v.value) {
+			cs.Modified = append(cs.Modified, k)
+		}
+	}
+	for k := range oldData {
+		if _, ok := new.data[k]; !ok {
+			cs.Removed = append(cs.Removed, k)
+		}
+	}
+	sort.Strings(cs.Added)
+	sort.Strings(cs.Removed)
+	sort.Strings(cs.Modified)
+	return cs
+}
@@ -0,0 +1,254 @@
+package conf
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//以通道接收配置变更内容
+type chanChange chan *ChangeSet
+
+func (c chanChange) ChangeHandel(fileName string, cs *ChangeSet) {
+	c <- cs
+}
+
+func TestChangeSet(t *testing.T) {
+	ch := make(chanChange, 10)
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t, WithChangeHandler(ch), WithCallback(cb))
+	_, err := cl.genConfigObject("change-test", SourceFile, map[string]interface{}{
+		"db":      map[string]interface{}{"dsn": "root@/a", "pool": int64(10)},
+		"feature": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := <-ch
+	<-cb
+	if cs.Old != nil || len(cs.Added) != 3 {
+		t.Errorf("首次加载时全部配置键应视为新增...%+v", cs)
+	}
+	_, err = cl.genConfigObject("change-test", SourceFile, map[string]interface{}{
+		"db":    map[string]interface{}{"dsn": "root@/b", "pool": int64(10)},
+		"debug": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs = <-ch
+	<-cb
+	if len(cs.Added) != 1 || cs.Added[0] != "debug" || len(cs.Removed) != 1 || cs.Removed[0] != "feature" ||
+		len(cs.Modified) != 1 || cs.Modified[0] != "db.dsn" {
+		t.Errorf("变更内容错误...%+v", cs)
+	}
+	if !cs.Changed("db.dsn") || cs.Changed("db.pool") {
+		t.Error("Changed判断错误...")
+	}
+	if cs.Old.Get("db.dsn").String() != "root@/a" || cs.New.Get("db.dsn").String() != "root@/b" {
+		t.Error("变更前后配置对象错误...")
+	}
+	// 配置内容没有变化时不回调
+	_, err = cl.genConfigObject("change-test", SourceFile, map[string]interface{}{
+		"db":    map[string]interface{}{"dsn": "root@/b", "pool": int64(10)},
+		"debug": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ch) != 0 || len(cb) != 0 {
+		t.Error("配置内容没有变化时不应回调...")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	cl := newTestClient(t)
+	load := func(dsn string, flag bool) *ConfigObject {
+		co, err := cl.genConfigObject("watch-test", SourceFile, map[string]interface{}{
+			"db":      map[string]interface{}{"master": dsn},
+			"feature": map[string]interface{}{"a": flag, "b": true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return co
+	}
+	c := load("root@/a", false)
+	db := make(chan [2]string, 10)
+	cancel := c.Watch("db.master", func(old *Result, new *Result) {
+		db <- [2]string{old.String(), new.String()}
+	})
+	features := make(chan string, 10)
+	c.Sub("feature").WatchPrefix("", func(key string, old *Result, new *Result) {
+		features <- key
+	})
+	ctx, stop := context.WithCancel(context.Background())
+	changes := c.Changes(ctx, "db.")
+
+	load("root@/b", false)
+	if v := <-db; v[0] != "root@/a" || v[1] != "root@/b" {
+		t.Errorf("配置键订阅回调错误...%v", v)
+	}
+	change := <-changes
+	if change.Key != "db.master" || change.New.String() != "root@/b" {
+		t.Errorf("通道订阅错误...%+v", change)
+	}
+
+	cancel()
+	load("root@/c", true)
+	if key := <-features; key != "a" {
+		t.Errorf("前缀订阅回调错误...%v", key)
+	}
+	<-changes
+	if len(db) != 0 || len(features) != 0 {
+		t.Error("取消订阅之后或者没有变化的配置键不应回调...")
+	}
+	stop()
+	for range changes {
+	}
+}
+
+func TestWatchTable(t *testing.T) {
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t, WithCallback(cb))
+	load := func(port int64) *ConfigObject {
+		co, err := cl.genConfigObject("watch-table", SourceFile, map[string]interface{}{
+			"db": map[string]interface{}{"master": map[string]interface{}{"addr": "10.0.0.1", "port": port}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return co
+	}
+	c := load(3306)
+	<-cb
+	ports := make(chan int64, 10)
+	c.Watch("db.master", func(old *Result, new *Result) {
+		ports <- new.Int()
+	})
+	// 不读取的通道不影响之后的回调
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	_ = c.Changes(ctx, "db.")
+	for port := int64(3307); port < 3307+changesBuffer*2; port++ {
+		load(port)
+		select {
+		case v := <-ports:
+			if v != port {
+				t.Fatalf("表订阅回调错误...%v", v)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("订阅的配置键为表时没有回调...")
+		}
+		<-cb
+	}
+}
+
+type panicCallback struct{}
+
+func (p *panicCallback) CallbackHandel(fileName string, co *ConfigObject) {
+	panic("callback panic")
+}
+
+//超过超时时间的回调函数，记录是否有并发执行
+type slowCallback struct {
+	running    int32
+	concurrent int32
+	calls      int32
+}
+
+func (s *slowCallback) CallbackHandel(fileName string, co *ConfigObject) {
+	if atomic.AddInt32(&s.running, 1) > 1 {
+		atomic.StoreInt32(&s.concurrent, 1)
+	}
+	time.Sleep(200 * time.Millisecond)
+	atomic.AddInt32(&s.running, -1)
+	atomic.AddInt32(&s.calls, 1)
+}
+
+//阻塞直到通道关闭的回调函数
+type blockCallback chan struct{}
+
+func (b blockCallback) CallbackHandel(fileName string, co *ConfigObject) {
+	<-b
+}
+
+func TestCallbackDispatch(t *testing.T) {
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t, WithCallbackTimeout(50*time.Millisecond))
+	slow := new(slowCallback)
+	cl.AddCallback(new(panicCallback))
+	cl.AddCallback(slow)
+	cl.AddCallback(cb)
+	for i := 1; i <= 3; i++ {
+		_, err := cl.genConfigObject("dispatch-test", SourceFile, map[string]interface{}{"version": int64(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// panic以及超时的回调函数不影响之后的回调，且同一配置标志按顺序回调
+	for i := 1; i <= 3; i++ {
+		select {
+		case co := <-cb:
+			if co.Get("version").Int() != int64(i) {
+				t.Errorf("回调顺序错误...%v", co.Get("version").Int())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("回调函数没有被调用...")
+		}
+	}
+	// 超时的回调函数执行完毕之后才开始下一次回调
+	if atomic.LoadInt32(&slow.concurrent) != 0 || atomic.LoadInt32(&slow.calls) < 2 {
+		t.Errorf("超时的回调函数不应与下一次回调并发执行...%v", atomic.LoadInt32(&slow.calls))
+	}
+	cl.RemoveCallback(slow)
+	cl.RemoveCallback(cb)
+	_, err := cl.genConfigObject("dispatch-test", SourceFile, map[string]interface{}{"version": int64(4)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-cb:
+		t.Error("删除之后不应再回调...")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCallbackDispatchCoalesce(t *testing.T) {
+	cb := make(chanCallback, dispatchBuffer*2)
+	block := make(blockCallback)
+	cl := newTestClient(t, WithCallbackTimeout(0))
+	cl.AddCallback(block)
+	cl.AddCallback(cb)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= dispatchBuffer*3; i++ {
+			_, err := cl.genConfigObject("coalesce-test", SourceFile, map[string]interface{}{"version": int64(i)})
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	// 回调函数阻塞时不阻塞配置同步
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("回调函数阻塞时配置同步被阻塞...")
+	}
+	close(block)
+	var last int64
+	for last != dispatchBuffer*3 {
+		select {
+		case co := <-cb:
+			v := co.Get("version").Int()
+			if v <= last {
+				t.Fatalf("回调顺序错误...%v %v", last, v)
+			}
+			last = v
+		case <-time.After(5 * time.Second):
+			t.Fatalf("合并之后没有回调最新配置...%v", last)
+		}
+	}
+}
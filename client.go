@@ -0,0 +1,490 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Client 配置客户端，持有配置环境、配置缓存、回调函数、日志以及配置中心连接，多个客户端之间互不影响
+type Client struct {
+	//配置环境
+	env *env
+	//配置数据
+	data map[string]ConfigObject
+	//写锁定，后续如果加入热更新写的时候不允许读操作避免大并发情况下读取到不完整数据
+	mutex *sync.RWMutex
+	//是否将数据缓存在内存中
+	isCache bool
+	// 通过 SetCallbackFunc 设置的回调函数
+	handel CallbackHandel
+	// 通过 SetChangeFunc 设置的配置变更回调函数
+	changeHandel ChangeHandler
+	// 配置键变更订阅
+	subscriptions subscriptions
+	// 配置键绑定的命令行参数
+	flags flagBindings
+	// 配置校验函数，以配置标志区分
+	validators map[string][]Validator
+	// 配置声明，校验之前以默认值填充缺失的配置键
+	schemas map[string][]Schema
+	// 通过 WithErrorHandler 设置的配置更新失败回调函数
+	errorHandel ErrorHandler
+	// 回调分发
+	dispatcher *dispatcher
+	// 单个回调函数超时时间
+	callbackTimeout time.Duration
+	// 日志
+	log *loger
+	// 配置中心TCP连接，以配置标志区分
+	tcpClients map[string]*xdiamondTCP
+	// 是否监听本地配置文件变更
+	fileWatch bool
+	// 已在监听变更的配置标志
+	watching map[string]bool
+	// 客户端生命周期，关闭客户端时取消
+	ctx    context.Context
+	cancel context.CancelFunc
+	// 环境变量覆盖配置的前缀，为空时不覆盖
+	envPrefix string
+	// 是否解析配置值中的引用，默认不解析
+	interpolation bool
+	// 以下为实例化参数
+	confPath string
+	envName  string
+	logDir   bool
+}
+
+// Option 客户端实例化参数
+type Option func(*Client)
+
+// WithConfigPath 指定配置路径，未指定时取环境变量 WEB_GO_CONFIG_PATH
+func WithConfigPath(path string) Option {
+	return func(cl *Client) {
+		cl.confPath = path
+	}
+}
+
+// WithEnv 指定配置环境，未指定时取环境变量 WEB_GO_CONFIG_ENV
+func WithEnv(env string) Option {
+	return func(cl *Client) {
+		cl.envName = env
+	}
+}
+
+// WithoutCache 禁止在内存中缓冲配置数据，同 DisableCache
+func WithoutCache() Option {
+	return func(cl *Client) {
+		cl.isCache = false
+	}
+}
+
+// WithCallback 设置回调函数，同 SetCallbackFunc
+func WithCallback(handel CallbackHandel) Option {
+	return func(cl *Client) {
+		cl.handel = handel
+	}
+}
+
+// WithChangeHandler 设置配置变更回调函数，同 SetChangeFunc
+func WithChangeHandler(handel ChangeHandler) Option {
+	return func(cl *Client) {
+		cl.changeHandel = handel
+	}
+}
+
+// WithErrorHandler 设置配置更新失败回调函数，比如配置中心推送的配置校验不通过
+func WithErrorHandler(handel ErrorHandler) Option {
+	return func(cl *Client) {
+		cl.errorHandel = handel
+	}
+}
+
+// WithCallbackTimeout 设置单个回调函数的超时时间，超时之后不再等待此回调函数，继续调用下一个回调函数，为0时一直等待，默认10秒。
+// 超时的回调函数不会被中断而是继续执行，同一配置标志的下一次回调在其执行完毕之后才开始
+func WithCallbackTimeout(timeout time.Duration) Option {
+	return func(cl *Client) {
+		cl.callbackTimeout = timeout
+	}
+}
+
+// WithEnvPrefix 设置环境变量覆盖配置的前缀，默认为 WEB_GO_CONFIG，为空时不以环境变量覆盖配置。
+// 环境变量名称中配置标志的"."以"_"代替，因此 comm.app 与 comm_app 对应同一环境变量;
+// 环境变量名称只能包含字母、数字以及"_"，含有其他字符(比如"-")的配置标志以及配置键无法覆盖
+func WithEnvPrefix(prefix string) Option {
+	return func(cl *Client) {
+		cl.envPrefix = prefix
+	}
+}
+
+// WithInterpolation 解析配置值中的 ${other.key}、${env:HOME}、${file:/run/secrets/x} 引用，引用不存在时加载失败
+func WithInterpolation() Option {
+	return func(cl *Client) {
+		cl.interpolation = true
+	}
+}
+
+// WithFileWatch 监听本地配置文件变更，变更时重新解析并回调，同 EnableFileWatch
+func WithFileWatch() Option {
+	return func(cl *Client) {
+		cl.fileWatch = true
+	}
+}
+
+// WithLogOutput 设置客户端日志输出以及日志级别
+func WithLogOutput(w io.Writer, lv LogLevel) Option {
+	return func(cl *Client) {
+		cl.log = newLoger(w)
+		cl.log.logLevel = lv
+	}
+}
+
+// WithoutLogFile 不读取公共配置 comm.log 中的日志目录，日志保持原输出
+func WithoutLogFile() Option {
+	return func(cl *Client) {
+		cl.logDir = false
+	}
+}
+
+// 使用已有的日志对象，默认客户端使用全局日志 Log
+func withLoger(l *loger) Option {
+	return func(cl *Client) {
+		cl.log = l
+	}
+}
+
+// New 实例化一个配置客户端
+func New(opts ...Option) (*Client, error) {
+	cl := &Client{
+		data:            make(map[string]ConfigObject),
+		mutex:           new(sync.RWMutex),
+		isCache:         true,
+		tcpClients:      make(map[string]*xdiamondTCP),
+		watching:        make(map[string]bool),
+		validators:      make(map[string][]Validator),
+		schemas:         make(map[string][]Schema),
+		logDir:          true,
+		callbackTimeout: defaultCallbackTimeout,
+		envPrefix:       envOverridePrefix,
+	}
+	for _, opt := range opts {
+		opt(cl)
+	}
+	if cl.log == nil {
+		cl.log = newLoger(os.Stdout)
+	}
+	var err error
+	cl.env, err = newEnv(cl.confPath, cl.envName)
+	if err != nil {
+		return nil, err
+	}
+	cl.ctx, cl.cancel = context.WithCancel(context.Background())
+	cl.dispatcher = newDispatcher(cl.ctx, cl.log, cl.callbackTimeout)
+	if cl.handel != nil {
+		cl.dispatcher.addCallback(cl.handel)
+	}
+	if cl.changeHandel != nil {
+		cl.dispatcher.addChangeHandler(cl.changeHandel)
+	}
+	if cl.errorHandel != nil {
+		cl.dispatcher.addErrorHandler(cl.errorHandel)
+	}
+	if cl.logDir {
+		err = cl.setLogDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cl, nil
+}
+
+// 设置日志路径,在此之前打印的信息还是会输出到终端
+func (cl *Client) setLogDir() error {
+	confMap, err := newLocalFile(cl.env).Load("comm.log")
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	kvMap := make(map[string]Result)
+	err = setKvMap(confMap, make(confKeys, 0), kvMap)
+	if err != nil {
+		return newError(ErrParse, "comm.log", SourceFile, err)
+	}
+	logDir, ok := kvMap["base.dir"]
+	dir := logDir.String()
+	if !ok || dir == "" {
+		return nil
+	}
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		err = os.MkdirAll(dir, 0775)
+		if err != nil {
+			return newError(ErrSource, "comm.log", SourceFile, err)
+		}
+	} else if !dirInfo.IsDir() {
+		return newError(ErrSource, "comm.log", SourceFile, errors.New(dir+" : 不是一个有效的目录"))
+	}
+	logFileName := dir + "/conf.log"
+	fileInfo, err := os.OpenFile(logFileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0664)
+	if err != nil {
+		cl.log.Error("日志文件打开失败...", err)
+		return nil
+	}
+	cl.log.setOutput(fileInfo)
+	return nil
+}
+
+// NewConfig 实例化一个配置对象，加载失败时中断程序执行
+func (cl *Client) NewConfig(fileName string, source Source) *ConfigObject {
+	co, err := cl.LoadConfig(fileName, source)
+	if err != nil {
+		cl.log.Fatal(err)
+	}
+	return co
+}
+
+// LoadConfig 实例化一个配置对象，加载失败时返回错误而不中断程序执行，错误类型为 *Error
+func (cl *Client) LoadConfig(fileName string, source Source) (*ConfigObject, error) {
+	switch source {
+	case SourceFile:
+		return cl.getConfigObject(fileName, source, newLocalFile(cl.env))
+	case SourceDotenv:
+		return cl.getConfigObject(fileName, source, newDotenvFile(cl.env))
+	case SourceXdaHTTP:
+		obj, err := newXdiamondHTTP(cl.env)
+		if err != nil {
+			return nil, newError(ErrSource, fileName, source, err)
+		}
+		return cl.getConfigObject(fileName, source, obj)
+	case SourceXdaTCP:
+		obj, err := newXdiamondTCP(cl)
+		if err != nil {
+			return nil, newError(ErrSource, fileName, source, err)
+		}
+		return cl.getConfigObject(fileName, source, obj)
+	case SourceBackups:
+		return new(ConfigObject), nil
+	case SourceLayered:
+		cl.mutex.RLock()
+		object, ok := cl.data[fileName]
+		cl.mutex.RUnlock()
+		if !ok {
+			return nil, newError(ErrNotFound, fileName, source, errors.New("未通过 NewLayeredConfig 合并"))
+		}
+		return &object, nil
+	}
+	if p, ok := getProvider(source); ok {
+		if ep, ok := p.(EnvProvider); ok {
+			p = ep.ForEnv(cl.env.env)
+		}
+		return cl.getConfigObject(fileName, source, p)
+	}
+	return nil, newError(ErrUnsupportedSource, fileName, source, nil)
+}
+
+// DisableCache 禁止在内存中缓冲配置数据
+func (cl *Client) DisableCache() {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	cl.isCache = false
+}
+
+// EnableFileWatch 监听本地配置文件变更(linux下基于inotify，其他系统轮询检测)，变更时重新解析、更新缓存并回调，对之后加载的配置生效
+func (cl *Client) EnableFileWatch() {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	cl.fileWatch = true
+}
+
+// SetCallbackFunc 设置回调函数，替换之前通过此方法设置的回调函数，通过 AddCallback 添加的回调函数不受影响
+func (cl *Client) SetCallbackFunc(handel CallbackHandel) {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	if cl.handel != nil {
+		cl.dispatcher.removeCallback(cl.handel)
+	}
+	cl.handel = handel
+	if handel != nil {
+		cl.dispatcher.addCallback(handel)
+	}
+}
+
+// AddCallback 添加一个回调函数，可以添加多个，按添加顺序调用
+func (cl *Client) AddCallback(handel CallbackHandel) {
+	cl.dispatcher.addCallback(handel)
+}
+
+// RemoveCallback 删除回调函数，回调函数需为可比较的类型(比如指针)
+func (cl *Client) RemoveCallback(handel CallbackHandel) {
+	cl.dispatcher.removeCallback(handel)
+}
+
+// SetChangeFunc 设置配置变更回调函数，回调时附带新增、删除以及修改的配置键，替换之前通过此方法设置的配置变更回调函数
+func (cl *Client) SetChangeFunc(handel ChangeHandler) {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	if cl.changeHandel != nil {
+		cl.dispatcher.removeChangeHandler(cl.changeHandel)
+	}
+	cl.changeHandel = handel
+	if handel != nil {
+		cl.dispatcher.addChangeHandler(handel)
+	}
+}
+
+// AddChangeHandler 添加一个配置变更回调函数，可以添加多个，按添加顺序调用
+func (cl *Client) AddChangeHandler(handel ChangeHandler) {
+	cl.dispatcher.addChangeHandler(handel)
+}
+
+// RemoveChangeHandler 删除配置变更回调函数，回调函数需为可比较的类型(比如指针)
+func (cl *Client) RemoveChangeHandler(handel ChangeHandler) {
+	cl.dispatcher.removeChangeHandler(handel)
+}
+
+// Close 关闭客户端，断开全部配置中心连接
+func (cl *Client) Close() error {
+	cl.cancel()
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	for fileName, x := range cl.tcpClients {
+		x.close()
+		delete(cl.tcpClients, fileName)
+	}
+	return nil
+}
+
+// getConfigObject 获取一个配置对象
+func (cl *Client) getConfigObject(fileName string, source Source, obj Provider) (*ConfigObject, error) {
+	cl.mutex.RLock()
+	cache := cl.isCache || source == SourceXdaTCP
+	object, ok := cl.data[fileName]
+	cl.mutex.RUnlock()
+	if cache && ok {
+		return &object, nil
+	}
+	tmp, err := obj.Load(fileName)
+	if err != nil {
+		//尝试从备份文件读取
+		if source.remote() {
+			cl.log.Warning("配置源", source, "连接失败..."+err.Error())
+			cl.log.Info("尝试从本地备份读取配置...")
+			tmps, err := cl.backupRecovery(fileName)
+			if err != nil {
+				return nil, newError(ErrBackupRecovery, fileName, source, err)
+			}
+			co, err := cl.genConfigObject(fileName, source, tmps)
+			if err != nil {
+				return nil, err
+			}
+			// 定时拉取时配置中心恢复之后会更新为最新配置
+			cl.startWatch(fileName, source, obj)
+			return co, nil
+		}
+		if _, ok := err.(*Error); ok {
+			return nil, err
+		}
+		return nil, newError(ErrParse, fileName, source, err)
+	}
+	if x, ok := obj.(*xdiamondTCP); ok {
+		cl.mutex.Lock()
+		cl.tcpClients[fileName] = x
+		cl.mutex.Unlock()
+	}
+	co, err := cl.genConfigObject(fileName, source, tmp)
+	if err != nil {
+		return nil, err
+	}
+	cl.startWatch(fileName, source, obj)
+	return co, nil
+}
+
+// 监听配置变更，同一配置标志只监听一次
+func (cl *Client) startWatch(fileName string, source Source, obj Provider) {
+	w, ok := obj.(Watcher)
+	if !ok {
+		return
+	}
+	cl.mutex.Lock()
+	if ((source == SourceFile || source == SourceDotenv) && !cl.fileWatch) || cl.watching[fileName] {
+		cl.mutex.Unlock()
+		return
+	}
+	cl.watching[fileName] = true
+	cl.mutex.Unlock()
+	err := w.Watch(cl.ctx, fileName, func(data map[string]interface{}, err error) {
+		if err != nil {
+			cl.reject(fileName, err)
+			return
+		}
+		cl.log.Info("配置", fileName, "有变更,重新加载...")
+		_, err = cl.genConfigObject(fileName, source, data)
+		if err != nil {
+			cl.reject(fileName, err)
+		}
+	})
+	if err != nil {
+		cl.log.Error("配置", fileName, "变更监听失败:", err)
+		cl.mutex.Lock()
+		delete(cl.watching, fileName)
+		cl.mutex.Unlock()
+	}
+}
+
+// 生成配置对象
+func (cl *Client) genConfigObject(fileName string, source Source, confMap map[string]interface{}) (*ConfigObject, error) {
+	kvMap := make(map[string]Result)
+	err := setKvMap(confMap, make(confKeys, 0), kvMap)
+	if err != nil {
+		return nil, newError(ErrParse, fileName, source, err)
+	}
+	overridden := cl.envOverride(fileName, kvMap)
+	// dotenv 文件解析时已展开变量，单引号内的配置值需保持原样
+	if source != SourceDotenv {
+		err = cl.interpolate(kvMap)
+		if err != nil {
+			return nil, newError(ErrParse, fileName, source, err)
+		}
+	}
+	co := ConfigObject{kvMap, true, source, fileName, cl, "", overridden}
+	cl.setDefaults(fileName, &co)
+	// 校验不通过时不覆盖备份，原配置继续生效
+	err = cl.validate(fileName, source, &co)
+	if err != nil {
+		return nil, err
+	}
+	// 配置中心等远程配置源数据备份
+	if source.remote() {
+		err = cl.backups(fileName, confMap)
+		if err != nil {
+			cl.log.Error(err)
+		}
+	}
+	cl.save(fileName, co)
+	return &co, nil
+}
+
+//  数据保存到内存，与原配置比对，配置内容有变化时异步调用回调函数
+func (cl *Client) save(fileName string, co ConfigObject) {
+	//写锁定
+	cl.mutex.Lock()
+	var old *ConfigObject
+	if prev, ok := cl.data[fileName]; ok {
+		old = &prev
+	}
+	cl.data[fileName] = co
+	cl.mutex.Unlock()
+	cs := diff(old, &co)
+	if old != nil && cs.Empty() {
+		return
+	}
+	cl.dispatcher.dispatch(&event{fileName: fileName, co: &co, cs: cs, subs: cl.subscriptions.get(fileName)})
+}
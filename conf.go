@@ -4,15 +4,17 @@ package conf
 import (
 	"errors"
 	"fmt"
-	"os"
 	"strings"
 	"sync"
 	"time"
 )
 
-//全局环境
-var e *env
-var c *conf
+// 默认客户端，供包级函数使用，首次使用时初始化
+var (
+	std     *Client
+	stdErr  error
+	stdOnce sync.Once
+)
 
 // Type 自定义类型，用于归纳基本数据类型
 type confType int
@@ -48,30 +50,17 @@ const (
 	SourceXdaTCP
 	// SourceBackups 配置来源，本地备份
 	SourceBackups
+	// SourceLayered 配置来源，多个配置层合并，见 NewLayeredConfig
+	SourceLayered
+	// SourceDotenv 配置来源，配置环境目录下 KEY=VALUE 格式的 dotenv 文件
+	SourceDotenv
 )
 
-//配置数据存储结构
-type conf struct {
-	//配置数据
-	data map[string]ConfigObject
-	//写锁定，后续如果加入热更新写的时候不允许读操作避免大并发情况下读取到不完整数据
-	mutex *sync.RWMutex
-	//是否将数据缓存在内存中
-	isCache bool
-	// 回调函数
-	handel CallbackHandel
-}
-
-// CallbackHandel 当配置有更新时调用此方法
+// CallbackHandel 当配置有更新时调用此方法，回调在独立的协程中按配置变更顺序进行，不会阻塞配置同步
 type CallbackHandel interface {
 	CallbackHandel(fileName string, co *ConfigObject)
 }
 
-//解析统一接口
-type analysis interface {
-	analysisConfig(fileName string) (map[string]interface{}, error)
-}
-
 //confKeys 自定义类型key
 type confKeys []string
 
@@ -80,140 +69,120 @@ func (k confKeys) toString() string {
 	return strings.Join(k, ".")
 }
 
-// 初始化必要的变量
-func init() {
-	var err error
-	logInit()
-	e, err = newEnv()
+// 获取默认客户端，默认客户端使用全局日志 Log
+func defaultClient() (*Client, error) {
+	stdOnce.Do(func() {
+		std, stdErr = New(withLoger(Log))
+	})
+	return std, stdErr
+}
+
+// 获取默认客户端，初始化失败时中断程序执行
+func mustDefaultClient() *Client {
+	cl, err := defaultClient()
 	if err != nil {
 		Log.Fatal(err)
 	}
-	c = &conf{
-		data:    make(map[string]ConfigObject),
-		mutex:   new(sync.RWMutex),
-		isCache: true,
-	}
-	// 设置日志路径,在此之前打印的信息还是会输出到终端
-	logConf := NewConfig("comm.log", SourceFile)
-	logDir := logConf.Get("base.dir")
-	dir := logDir.String()
-	if logDir.Exists() && dir != "" {
-		dirInfo, err := os.Stat(dir)
-		if err != nil {
-			if os.IsNotExist(err) {
-				err = os.MkdirAll(dir, 0664)
-				if err != nil {
-					Log.Fatal("创建日志目录失败:", err)
-				}
-			} else {
-				Log.Fatal(err)
-			}
-		} else if !dirInfo.IsDir() {
-			Log.Fatal(dir, " : 不是一个有效的目录")
-		}
-		logFileName := dir + "/conf.log"
-		fileInfo, err := os.OpenFile(logFileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0664)
-		if err != nil {
-			Log.Error("日志文件打开失败...", err)
-		}
-		SetLogOutput(fileInfo, All)
-	}
+	return cl
 }
 
-// NewConfig 实例化一个配置对象
+// NewConfig 使用默认客户端实例化一个配置对象，加载失败时中断程序执行
 func NewConfig(fileName string, source Source) *ConfigObject {
-	switch source {
-	case SourceFile:
-		return c.getConfigObject(fileName, source, newLocalFile())
-	case SourceXdaHTTP:
-		return c.getConfigObject(fileName, source, newXdiamondHTTP())
-	case SourceXdaTCP:
-		return c.getConfigObject(fileName, source, newXdiamondTCP())
-	case SourceBackups:
-		return new(ConfigObject)
+	return mustDefaultClient().NewConfig(fileName, source)
+}
+
+// LoadConfig 使用默认客户端实例化一个配置对象，加载失败时返回错误而不中断程序执行，错误类型为 *Error
+func LoadConfig(fileName string, source Source) (*ConfigObject, error) {
+	cl, err := defaultClient()
+	if err != nil {
+		return nil, newError(ErrSource, fileName, source, err)
 	}
-	return new(ConfigObject)
+	return cl.LoadConfig(fileName, source)
 }
 
-// DisableCache 禁止在内存中缓冲配置数据
+// DisableCache 禁止默认客户端在内存中缓冲配置数据
 func DisableCache() {
-	c.isCache = false
+	mustDefaultClient().DisableCache()
+}
+
+// EnableFileWatch 监听默认客户端加载的本地配置文件变更
+func EnableFileWatch() {
+	mustDefaultClient().EnableFileWatch()
 }
 
-// SetCallbackFunc 设置回调函数
+// SetCallbackFunc 设置默认客户端的回调函数
 func SetCallbackFunc(handel CallbackHandel) {
-	c.handel = handel
+	mustDefaultClient().SetCallbackFunc(handel)
 }
 
-// getConfigObject 获取一个配置对象
-func (c *conf) getConfigObject(fileName string, source Source, obj analysis) *ConfigObject {
-	if c.isCache || source == SourceXdaTCP {
-		object, ok := c.data[fileName]
-		if ok {
-			return &object
-		}
-	}
-	tmp, err := obj.analysisConfig(fileName)
-	if err != nil {
-		//尝试从备份文件读取
-		if source == SourceXdaHTTP || source == SourceXdaTCP {
-			Log.Warning("配置中心连接失败..." + err.Error())
-			Log.Info("尝试从本地备份读取配置...")
-			tmps, err := backupRecovery(fileName)
-			if err != nil {
-				Log.Fatal(err)
-			}
-			return c.genConfigObject(fileName, source, tmps)
-		}
-		Log.Fatal(err)
-	}
-	return c.genConfigObject(fileName, source, tmp)
+// AddCallback 为默认客户端添加一个回调函数
+func AddCallback(handel CallbackHandel) {
+	mustDefaultClient().AddCallback(handel)
 }
 
-// 生成配置对象
-func (c *conf) genConfigObject(fileName string, source Source, confMap map[string]interface{}) *ConfigObject {
-	kvMap := make(map[string]Result)
-	err := setKvMap(confMap, make(confKeys, 0), kvMap)
+// RemoveCallback 删除默认客户端的回调函数
+func RemoveCallback(handel CallbackHandel) {
+	mustDefaultClient().RemoveCallback(handel)
+}
+
+// AddChangeHandler 为默认客户端添加一个配置变更回调函数
+func AddChangeHandler(handel ChangeHandler) {
+	mustDefaultClient().AddChangeHandler(handel)
+}
+
+// RemoveChangeHandler 删除默认客户端的配置变更回调函数
+func RemoveChangeHandler(handel ChangeHandler) {
+	mustDefaultClient().RemoveChangeHandler(handel)
+}
+
+// SetChangeFunc 设置默认客户端的配置变更回调函数
+func SetChangeFunc(handel ChangeHandler) {
+	mustDefaultClient().SetChangeFunc(handel)
+}
+
+// NewLayeredConfig 使用默认客户端合并多个配置层
+func NewLayeredConfig(name string, layers ...Layer) (*LayeredConfig, error) {
+	cl, err := defaultClient()
 	if err != nil {
-		Log.Fatal(err)
-	}
-	co := ConfigObject{kvMap, true, source, fileName}
-	if c.isCache || source == SourceXdaTCP || source == SourceXdaHTTP {
-		// 配置中心数据备份
-		if source == SourceXdaTCP || source == SourceXdaHTTP {
-			err = backups(fileName, confMap)
-			if err != nil {
-				Log.Error(err)
-			}
-		}
-	}
-	c.save(fileName, co)
-	return &co
-}
-
-//  数据保存到内存
-func (c *conf) save(fileName string, co ConfigObject) {
-	//写锁定
-	c.mutex.Lock()
-	c.data[fileName] = co
-	c.mutex.Unlock()
-	//如果有设置回调函数，调用之
-	if c.handel != nil {
-		c.handel.CallbackHandel(fileName, &co)
+		return nil, newError(ErrSource, name, SourceLayered, err)
 	}
+	return cl.NewLayeredConfig(name, layers...)
+}
+
+// RegisterValidator 为默认客户端的指定配置标志注册校验函数
+func RegisterValidator(fileName string, v Validator) {
+	mustDefaultClient().RegisterValidator(fileName, v)
+}
+
+// RegisterSchema 为默认客户端的指定配置标志注册配置声明
+func RegisterSchema(fileName string, s Schema) {
+	mustDefaultClient().RegisterSchema(fileName, s)
+}
+
+// AddErrorHandler 为默认客户端添加一个配置更新失败回调函数
+func AddErrorHandler(handel ErrorHandler) {
+	mustDefaultClient().AddErrorHandler(handel)
+}
+
+// RemoveErrorHandler 删除默认客户端的配置更新失败回调函数
+func RemoveErrorHandler(handel ErrorHandler) {
+	mustDefaultClient().RemoveErrorHandler(handel)
 }
 
 // setKvMap 递归设置一个kvMap
 func setKvMap(m interface{}, keys confKeys, kvMap map[string]Result) error {
 	tmp, ok := m.(map[string]interface{})
 	if !ok {
-		return errors.New("类型断言失败,配置内容格式:" + fmt.Sprintf("%v", tmp))
+		return errors.New("类型断言失败,配置内容格式:" + fmt.Sprintf("%v", m))
 	}
 	for k, v := range tmp {
 		keyNodes := append(keys, k)
 		switch v.(type) {
 		case map[string]interface{}:
-			_ = setKvMap(v, keyNodes, kvMap)
+			err := setKvMap(v, keyNodes, kvMap)
+			if err != nil {
+				return err
+			}
 		default:
 			kvMap[keyNodes.toString()] = genResult(v)
 		}
@@ -226,20 +195,20 @@ func setKvMap(m interface{}, keys confKeys, kvMap map[string]Result) error {
 func genResult(v interface{}) Result {
 	switch v.(type) {
 	case []interface{}, []map[string]interface{}, [][]interface{}, [][]map[string]interface{}, map[string]interface{}:
-		return Result{Array, v, true}
+		return Result{Array, v, true, ""}
 	case string:
-		return Result{String, v, true}
+		return Result{String, v, true, ""}
 	case int, int8, int16, int32, int64:
-		return Result{Int, v, true}
+		return Result{Int, v, true, ""}
 	case uint, uint8, uint16, uint32, uint64:
-		return Result{Uint, v, true}
+		return Result{Uint, v, true, ""}
 	case float32, float64:
-		return Result{Float, v, true}
+		return Result{Float, v, true, ""}
 	case bool:
-		return Result{Bool, v, true}
+		return Result{Bool, v, true, ""}
 	case time.Time:
-		return Result{Time, v, true}
+		return Result{Time, v, true, ""}
 	default:
-		return Result{Undefined, v, true}
+		return Result{Undefined, v, true, ""}
 	}
 }
@@ -1,19 +1,30 @@
 package conf
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"os"
 	"testing"
 	"time"
+
+	"github.com/BurntSushi/toml"
 )
 
 func TestFile(t *testing.T) {
-	var err error
-	err = setEnv()
+	cl, err := setEnv()
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	err = createTestFile(cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := cl.LoadConfig("comm.app", SourceFile)
+	if err != nil {
+		t.Fatal(err)
 	}
-	c := NewConfig("comm.app", SourceFile)
 	if c.Get("title").String() != "TOML Example" {
 		t.Error("字符创类型数据读取错误...")
 	}
@@ -37,17 +48,108 @@ func TestFile(t *testing.T) {
 		t.Errorf("嵌套配置数据读取错误...%T", c.Get("clients.data").Value())
 	}
 }
+func TestSub(t *testing.T) {
+	c := newTestObject(t, `
+	[crm.redis]
+	addr = "localhost:6379"
+	db = 1
+	[crm.redis.slave]
+	addr = "localhost:6380"
+	[crm.mysql]
+	dsn = "root@/crm"
+	`)
+	sub := c.Sub("crm.redis")
+	if !sub.Exists() || sub.Get("addr").String() != "localhost:6379" || sub.Get("slave.addr").String() != "localhost:6380" {
+		t.Errorf("配置子集读取错误...%v", sub.All())
+	}
+	if c.Sub("crm.none").Exists() {
+		t.Error("不存在的配置子集应返回空配置对象...")
+	}
+	if keys := c.Keys("crm.redis"); len(keys) != 3 || keys[0] != "crm.redis.addr" {
+		t.Errorf("配置键读取错误...%v", keys)
+	}
+	if children := c.Children("crm"); len(children) != 2 || children[0] != "mysql" || children[1] != "redis" {
+		t.Errorf("子节点读取错误...%v", children)
+	}
+}
+
+func TestLoadConfigNotFound(t *testing.T) {
+	cl, err := setEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cl.LoadConfig("comm.not_exists", SourceFile)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("配置不存在时应返回ErrNotFound...%v", err)
+	}
+}
+
+func TestClientOptionsConcurrent(t *testing.T) {
+	cl := newTestClient(t)
+	writeTestFile(t, cl, "comm/app.toml", "port = 80")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cl.DisableCache()
+		cl.EnableFileWatch()
+	}()
+	for i := 0; i < 10; i++ {
+		_, err := cl.LoadConfig("comm.app", SourceFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
+
 func TestXdiamondHTTP(t *testing.T) {
-	err := createXdiamondConf()
+	cl, err := setEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = createXdiamondConf(cl)
 	if err != nil {
 		t.Error(err)
 	}
-	c := NewConfig("golang-test.1.0", SourceXdaHTTP)
+	c := cl.NewConfig("golang-test.1.0", SourceXdaHTTP)
 	if c.Get("test2").String() != "x" {
 		t.Error("配置中心http读取错误...")
 	}
 }
 
+func TestXdiamondTCPTimeout(t *testing.T) {
+	// 接受连接但不响应的配置中心
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	timeout := tcpLoadTimeout
+	tcpLoadTimeout = 100 * time.Millisecond
+	defer func() {
+		tcpLoadTimeout = timeout
+	}()
+	cl := newTestClient(t)
+	writeTestFile(t, cl, "comm/xdiamond.toml", "group_id = \"web\"\ntcp_address = \""+l.Addr().String()+"\"")
+	err = cl.backups("timeout-test.1.0", map[string]interface{}{"port": int64(8080)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := cl.LoadConfig("timeout-test.1.0", SourceXdaTCP)
+	if err != nil || c.Get("port").Int() != 8080 {
+		t.Errorf("配置中心没有响应时应从本地备份恢复...%v", err)
+	}
+}
+
 type callback struct {
 }
 
@@ -55,36 +157,94 @@ func (c *callback) CallbackHandel(fileName string, co *ConfigObject) {
 	fmt.Println("配置变更回调")
 }
 func TestXdiamondTCP(t *testing.T) {
-	err := createXdiamondConf()
+	cl, err := setEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	err = createXdiamondConf(cl)
 	if err != nil {
 		t.Error(err)
 	}
 	cb := new(callback)
-	SetCallbackFunc(cb)
-	c := NewConfig("golang-test.1.0", SourceXdaTCP)
+	cl.SetCallbackFunc(cb)
+	c := cl.NewConfig("golang-test.1.0", SourceXdaTCP)
 	if c.Get("test2").String() != "x" {
 		t.Error("配置中心TCP读取错误...")
 	}
 }
 
-//重置环境
-func setEnv() error {
-	path := os.TempDir()
-	e.confDir = path + "/"
-	confPath := path + "comm"
+//以toml文本生成一个配置对象
+func newTestObject(t *testing.T, body string) *ConfigObject {
+	var data = make(map[string]interface{})
+	_, err := toml.Decode(body, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kvMap := make(map[string]Result)
+	err = setKvMap(data, make(confKeys, 0), kvMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ConfigObject{data: kvMap, isExistence: true, source: SourceFile, fileName: "test"}
+}
+
+//以独立的临时目录作为配置路径实例化一个客户端
+func newTestClient(t *testing.T, opts ...Option) *Client {
+	path, err := ioutil.TempDir("", "web_go_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(path)
+	})
+	err = os.MkdirAll(path+"/dev/comm", 0775)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = append([]Option{WithConfigPath(path), WithEnv("dev"), WithoutLogFile(), WithLogOutput(ioutil.Discard, All)}, opts...)
+	cl, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = cl.Close()
+	})
+	return cl
+}
+
+//在客户端配置目录下写入一个配置文件
+func writeTestFile(t *testing.T, cl *Client, name string, body string) {
+	err := ioutil.WriteFile(cl.env.confDir+name, []byte(body), 0664)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+//以通道接收配置变更回调
+type chanCallback chan *ConfigObject
+
+func (c chanCallback) CallbackHandel(fileName string, co *ConfigObject) {
+	c <- co
+}
+
+//重置环境，以临时目录作为配置路径实例化一个客户端
+func setEnv() (*Client, error) {
+	path := os.TempDir() + "/web_go_config"
+	confPath := path + "/dev/comm"
 	_, err := os.Stat(confPath)
 	if err != nil {
-		err = os.MkdirAll(confPath, 0666)
+		err = os.MkdirAll(confPath, 0775)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return nil
+	return New(WithConfigPath(path), WithEnv("dev"), WithoutLogFile())
 }
 
 //设置一个测试文件
-func createTestFile() error {
-	fileName := e.confDir + "comm/app.toml"
+func createTestFile(cl *Client) error {
+	fileName := cl.env.confDir + "comm/app.toml"
 	fileInfo, err := os.Create(fileName)
 	defer fileInfo.Close()
 	if err != nil {
@@ -127,8 +287,8 @@ func createTestFile() error {
 }
 
 // 创建用户中心配置文件
-func createXdiamondConf() error {
-	fileName := e.confDir + "comm/xdiamond.toml"
+func createXdiamondConf(cl *Client) error {
+	fileName := cl.env.confDir + "comm/xdiamond.toml"
 	fileInfo, err := os.Create(fileName)
 	defer fileInfo.Close()
 	if err != nil {
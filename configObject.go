@@ -2,7 +2,9 @@ package conf
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +17,12 @@ type ConfigObject struct {
 	source Source
 	//fileName 配置文件标志
 	fileName string
+	//所属客户端，用于订阅配置变更
+	client *Client
+	//配置子集的前缀，Sub 返回的配置对象订阅变更时以此补全配置键
+	prefix string
+	//被环境变量覆盖的配置键
+	overridden []string
 }
 
 //Result 配置数据解析结果
@@ -23,24 +31,102 @@ type Result struct {
 	value    interface{}
 	//标记是否存在
 	isExistence bool
+	//配置键，用于类型转换错误
+	key string
 }
 
-//Get 获取一个配置结果
+//Get 获取一个配置结果，绑定的命令行参数显式设置时返回参数值
 func (c *ConfigObject) Get(key string) *Result {
 	if key == "" {
 		return new(Result)
 	}
-	r, ok := c.data[key]
+	r, ok := c.lookup(key)
 	if ok {
+		r.key = key
 		return &r
 	}
-	return new(Result)
+	return &Result{key: key}
 
 }
 
-// All 获取全部配置
+// All 获取全部配置，包含命令行中显式设置的绑定参数
 func (c *ConfigObject) All() map[string]Result {
-	return c.data
+	return c.merged()
+}
+
+// Keys 获取指定前缀下的全部配置键(完整路径)，前缀为空时返回全部配置键，结果按字典序排列
+func (c *ConfigObject) Keys(prefix string) []string {
+	keys := make([]string, 0)
+	for k := range c.merged() {
+		if prefix == "" || strings.HasPrefix(k, prefix+".") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Children 获取指定前缀下一级子节点名称，比如配置有 servers.alpha.ip 和 servers.beta.ip 时 Children("servers") 返回 [alpha beta]
+func (c *ConfigObject) Children(prefix string) []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, k := range c.Keys(prefix) {
+		if prefix != "" {
+			k = k[len(prefix)+1:]
+		}
+		if i := strings.Index(k, "."); i != -1 {
+			k = k[:i]
+		}
+		if !seen[k] {
+			seen[k] = true
+			names = append(names, k)
+		}
+	}
+	return names
+}
+
+// Sub 获取指定前缀下的配置子集，返回的配置对象中配置键不再包含此前缀，比如 c.Sub("crm.redis").Get("addr") 等同于 c.Get("crm.redis.addr")
+func (c *ConfigObject) Sub(prefix string) *ConfigObject {
+	if prefix == "" {
+		return c
+	}
+	data := make(map[string]Result)
+	for k, v := range c.merged() {
+		if strings.HasPrefix(k, prefix+".") {
+			data[k[len(prefix)+1:]] = v
+		}
+	}
+	overridden := make([]string, 0)
+	for _, k := range c.overridden {
+		if strings.HasPrefix(k, prefix+".") {
+			overridden = append(overridden, k[len(prefix)+1:])
+		}
+	}
+	return &ConfigObject{data, len(data) > 0, c.source, c.fileName, c.client, joinKey(c.prefix, prefix), overridden}
+}
+
+// StringMap 以map[string]string返回指定前缀下的全部配置，键为去掉前缀之后的配置键，比如表 [labels] 下的全部配置
+func (c *ConfigObject) StringMap(prefix string) map[string]string {
+	v := make(map[string]string)
+	for k, r := range c.Sub(prefix).data {
+		v[k] = r.String()
+	}
+	return v
+}
+
+// Overridden 获取被环境变量覆盖的配置键，按字典序排列
+func (c *ConfigObject) Overridden() []string {
+	return append([]string(nil), c.overridden...)
+}
+
+// 判断配置键下是否存在子节点
+func (c *ConfigObject) hasChildren(prefix string) bool {
+	for k := range c.merged() {
+		if prefix == "" || strings.HasPrefix(k, prefix+".") {
+			return true
+		}
+	}
+	return false
 }
 
 // Exists 判断是否存在此配置对象
@@ -96,32 +182,52 @@ func (r *Result) Slice() []interface{} {
     password = ""
     db = 0
 **/
+// 同时支持json备份中解码得到的[]interface{}，元素不是表时忽略此元素
 func (r *Result) SliceMap() []map[string]interface{} {
-	v, ok := r.value.([]map[string]interface{})
-	if !ok {
-		return make([]map[string]interface{}, 0, 0)
+	switch v := r.value.(type) {
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		maps := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			m, ok := item.(map[string]interface{})
+			if ok {
+				maps = append(maps, m)
+			}
+		}
+		return maps
 	}
-	return v
+	return make([]map[string]interface{}, 0, 0)
 }
 
 // Time 以时间格式返回配置值，时间格式依照toml以RFC3339因特网标准时间为准
 func (r *Result) Time() time.Time {
-	if r.dataType == Time {
+	t, _ := r.TimeE()
+	return t
+}
+
+// TimeE 以时间格式返回配置值，配置不存在或无法转换时返回错误
+func (r *Result) TimeE() (time.Time, error) {
+	if !r.isExistence {
+		return time.Unix(0, 0), r.convertError("time.Time", ErrNotFound)
+	}
+	switch r.dataType {
+	case Time:
 		v, ok := r.value.(time.Time)
 		if ok {
-			return v
+			return v, nil
 		}
-	}
-	if r.dataType == String {
+	case String:
 		v, ok := r.value.(string)
 		if ok {
 			t, err := time.Parse(time.RFC3339, v)
 			if err == nil {
-				return t
+				return t, nil
 			}
+			return time.Unix(0, 0), r.convertError("time.Time", err)
 		}
 	}
-	return time.Unix(0, 0)
+	return time.Unix(0, 0), r.convertError("time.Time", nil)
 }
 
 // ToDateTime 尝试以Y-m-d h:i:s的格式返回时间配置值字符串
@@ -132,115 +238,287 @@ func (r *Result) ToDateTime() string {
 
 // Bool 以布尔型返回配置值
 func (r *Result) Bool() bool {
+	b, _ := r.BoolE()
+	return b
+}
+
+// BoolE 以布尔型返回配置值，配置不存在或无法转换时返回错误
+func (r *Result) BoolE() (bool, error) {
+	if !r.isExistence {
+		return false, r.convertError("bool", ErrNotFound)
+	}
 	switch r.dataType {
 	case String:
 		v, ok := r.value.(string)
 		if !ok {
-			return false
+			break
 		}
 		n, err := strconv.ParseBool(v)
 		if err != nil {
-			return false
+			return false, r.convertError("bool", err)
 		}
-		return n
+		return n, nil
 	case Int, Uint, Float:
-		v, ok := r.value.(float64)
-		if !ok {
-			return false
+		v, err := toFloat64(r.value)
+		if err != nil {
+			return false, r.convertError("bool", err)
 		}
-		return v != 0
+		return v != 0, nil
 	case Bool:
 		v, ok := r.value.(bool)
 		if !ok {
-			return false
+			break
 		}
-		return v
+		return v, nil
 	case Array:
 		v, ok := r.value.([]interface{})
 		if !ok {
-			return false
+			break
 		}
-		return len(v) > 0
+		return len(v) > 0, nil
 	case Time:
 		v, ok := r.value.(time.Time)
 		if !ok {
-			return false
+			break
 		}
-		return v.Unix() > 0
+		return v.Unix() > 0, nil
 	case Undefined:
-		return !(r.value == nil)
-	default:
-		return false
+		return !(r.value == nil), nil
 	}
+	return false, r.convertError("bool", nil)
 }
 
 // Float 以浮点类型返回配置值
 func (r *Result) Float() float64 {
+	f, _ := r.FloatE()
+	return f
+}
+
+// FloatE 以浮点类型返回配置值，配置不存在或无法转换时返回错误
+func (r *Result) FloatE() (float64, error) {
+	if !r.isExistence {
+		return float64(0), r.convertError("float64", ErrNotFound)
+	}
 	switch r.dataType {
 	case String:
 		v, ok := r.value.(string)
 		if !ok {
-			return float64(0)
+			break
 		}
 		n, err := strconv.ParseFloat(v, 64)
 		if err != nil {
-			return float64(0)
+			return float64(0), r.convertError("float64", err)
 		}
-		return n
+		return n, nil
 	case Int, Uint, Float:
-		v, ok := r.value.(float64)
-		if !ok {
-			return float64(0)
+		v, err := toFloat64(r.value)
+		if err != nil {
+			return float64(0), r.convertError("float64", err)
 		}
-		return float64(v)
+		return v, nil
 	case Bool:
 		v, ok := r.value.(bool)
 		if !ok {
-			return float64(0)
+			break
 		}
 		if v {
-			return float64(1)
+			return float64(1), nil
 		}
-	default:
-		return float64(0)
+		return float64(0), nil
 	}
-	return float64(0)
+	return float64(0), r.convertError("float64", nil)
 }
 
 // Int 以int64类型返回配置值
 func (r *Result) Int() int64 {
+	n, _ := r.IntE()
+	return n
+}
+
+// IntE 以int64类型返回配置值，配置不存在或无法转换时返回错误
+func (r *Result) IntE() (int64, error) {
+	if !r.isExistence {
+		return int64(0), r.convertError("int64", ErrNotFound)
+	}
 	switch r.dataType {
 	case String:
 		v, ok := r.value.(string)
 		if !ok {
-			return int64(0)
+			break
 		}
 		n, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
-			return int64(0)
+			return int64(0), r.convertError("int64", err)
 		}
-		return n
+		return n, nil
 	case Int, Uint, Float:
-		v, ok := r.value.(int64)
-		if !ok {
-			return int64(0)
+		v, err := toInt64(r.value)
+		if err != nil {
+			return int64(0), r.convertError("int64", err)
 		}
-		return int64(v)
+		return v, nil
 	case Bool:
 		v, ok := r.value.(bool)
 		if !ok {
-			return int64(0)
+			break
 		}
 		if v {
-			return int64(1)
+			return int64(1), nil
 		}
-	default:
-		return int64(0)
+		return int64(0), nil
 	}
-	return int64(0)
+	return int64(0), r.convertError("int64", nil)
 }
 
-// Uint 以uint64返回配置值，注意：负数转无符号数得到的值可能不是你预期的
+// Uint 以uint64返回配置值，负数或者超出范围时返回0
 func (r *Result) Uint() uint64 {
-	return uint64(r.Int())
+	n, _ := r.UintE()
+	return n
+}
+
+// UintE 以uint64类型返回配置值，配置不存在、无法转换、为负数或者超出范围时返回错误
+func (r *Result) UintE() (uint64, error) {
+	if !r.isExistence {
+		return 0, r.convertError("uint64", ErrNotFound)
+	}
+	switch r.dataType {
+	case String:
+		v, ok := r.value.(string)
+		if !ok {
+			break
+		}
+		if strings.HasPrefix(v, "-") {
+			return 0, r.convertError("uint64", errNegative)
+		}
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return 0, r.convertError("uint64", err)
+		}
+		return n, nil
+	case Int, Uint, Float:
+		v, err := toUint64(r.value)
+		if err != nil {
+			return 0, r.convertError("uint64", err)
+		}
+		return v, nil
+	case Bool:
+		v, ok := r.value.(bool)
+		if !ok {
+			break
+		}
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, r.convertError("uint64", nil)
+}
+
+// Duration 以时间间隔返回配置值，支持 "1m30s" 这样的字符串，数值以秒为单位
+func (r *Result) Duration() time.Duration {
+	d, _ := r.DurationE()
+	return d
+}
+
+// DurationE 以时间间隔返回配置值，配置不存在或无法转换时返回错误
+func (r *Result) DurationE() (time.Duration, error) {
+	if !r.isExistence {
+		return 0, r.convertError("time.Duration", ErrNotFound)
+	}
+	switch r.dataType {
+	case String:
+		v, ok := r.value.(string)
+		if !ok {
+			break
+		}
+		d, err := time.ParseDuration(v)
+		if err == nil {
+			return d, nil
+		}
+		n, nErr := strconv.ParseFloat(v, 64)
+		if nErr != nil {
+			return 0, r.convertError("time.Duration", err)
+		}
+		return time.Duration(n * float64(time.Second)), nil
+	case Int, Uint:
+		n, err := r.IntE()
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * time.Second, nil
+	case Float:
+		f, err := r.FloatE()
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	return 0, r.convertError("time.Duration", nil)
+}
+
+// MustInt 以int64类型返回配置值，配置不存在或无法转换时 panic，适合在启动时读取必要配置
+func (r *Result) MustInt() int64 {
+	n, err := r.IntE()
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// MustUint 以uint64类型返回配置值，配置不存在、无法转换或为负数时 panic
+func (r *Result) MustUint() uint64 {
+	n, err := r.UintE()
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// MustFloat 以浮点类型返回配置值，配置不存在或无法转换时 panic
+func (r *Result) MustFloat() float64 {
+	f, err := r.FloatE()
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// MustBool 以布尔型返回配置值，配置不存在或无法转换时 panic
+func (r *Result) MustBool() bool {
+	b, err := r.BoolE()
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// MustTime 以时间格式返回配置值，配置不存在或无法转换时 panic
+func (r *Result) MustTime() time.Time {
+	t, err := r.TimeE()
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// MustDuration 以时间间隔返回配置值，配置不存在或无法转换时 panic
+func (r *Result) MustDuration() time.Duration {
+	d, err := r.DurationE()
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// MustString 以字符串返回配置值，配置不存在时 panic
+func (r *Result) MustString() string {
+	if !r.isExistence {
+		panic(r.convertError("string", ErrNotFound))
+	}
+	return r.String()
+}
+
+// 生成类型转换错误
+func (r *Result) convertError(to string, err error) error {
+	return &ConvertError{Key: r.key, Value: r.value, To: to, Err: err}
 }
@@ -0,0 +1,220 @@
+package conf
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultE(t *testing.T) {
+	c := newTestObject(t, `
+	int = 1
+	str = "abc"
+	timeout = "1m30s"
+	seconds = 30
+	dob = "2018-05-27"
+	negative = -1
+	`)
+	if n, err := c.Get("int").IntE(); err != nil || n != 1 {
+		t.Errorf("IntE读取错误...%v %v", n, err)
+	}
+	if _, err := c.Get("str").IntE(); err == nil {
+		t.Error("无法转换的配置值应返回错误...")
+	} else if ce, ok := err.(*ConvertError); !ok || ce.Key != "str" {
+		t.Errorf("类型转换错误应包含配置键...%v", err)
+	}
+	if _, err := c.Get("str").BoolE(); err == nil {
+		t.Error("无法转换的配置值应返回错误...")
+	}
+	if _, err := c.Get("dob").TimeE(); err == nil {
+		t.Error("无法解析的时间应返回错误...")
+	}
+	if _, err := c.Get("negative").UintE(); err == nil {
+		t.Error("负数转无符号数应返回错误...")
+	}
+	if _, err := c.Get("none").FloatE(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("配置不存在时应返回ErrNotFound...%v", err)
+	}
+	if d, err := c.Get("timeout").DurationE(); err != nil || d != 90*time.Second {
+		t.Errorf("DurationE读取错误...%v %v", d, err)
+	}
+	if d := c.Get("seconds").Duration(); d != 30*time.Second {
+		t.Errorf("整数应以秒为单位...%v", d)
+	}
+	defer func() {
+		err, _ := recover().(error)
+		if err == nil || !strings.Contains(err.Error(), "配置[str]") {
+			t.Errorf("MustInt无法转换时应以包含配置键的错误panic...%v", err)
+		}
+	}()
+	c.Get("str").MustInt()
+}
+
+func TestTypedAccessors(t *testing.T) {
+	c := newTestObject(t, `
+	buffer = "64MB"
+	cache = "1.5 GiB"
+	raw = 512
+	bad = "12XB"
+	endpoint = "https://example.com:8443/api"
+	host = "example.com"
+	ip = "10.0.0.1"
+	cidr = "10.0.0.0/8"
+	`)
+	if c.Get("buffer").ByteSize() != 64<<20 || c.Get("cache").ByteSize() != 3<<29 || c.Get("raw").Bytes() != 512 {
+		t.Error("容量配置解析错误...")
+	}
+	if _, err := c.Get("bad").BytesE(); err == nil {
+		t.Error("无法识别的容量单位应返回错误...")
+	}
+	if u := c.Get("endpoint").URL(); u == nil || u.Port() != "8443" {
+		t.Errorf("URL解析错误...%v", u)
+	}
+	if _, err := c.Get("host").URLE(); err == nil {
+		t.Error("缺少协议的地址应返回错误...")
+	}
+	if ip := c.Get("ip").IP(); ip == nil || ip.String() != "10.0.0.1" {
+		t.Errorf("IP解析错误...%v", ip)
+	}
+	if n := c.Get("cidr").CIDR(); n == nil || !n.Contains(c.Get("ip").IP()) {
+		t.Errorf("网段解析错误...%v", n)
+	}
+	if _, err := c.Get("host").IPE(); err == nil {
+		t.Error("无效的IP地址应返回错误...")
+	}
+}
+
+func TestSliceAccessors(t *testing.T) {
+	c := newTestObject(t, `
+	ports = [8001, 8002]
+	ratios = [0.5, 1.5]
+	flags = [true, false]
+	hosts = "alpha, omega,"
+	labels = "env=dev, team = web"
+	[[slave]]
+	addr = "localhost:6379"
+	[table]
+	a = "1"
+	b = 2
+	`)
+	if v := c.Get("ports").IntSlice(); len(v) != 2 || v[1] != 8002 {
+		t.Errorf("IntSlice读取错误...%v", v)
+	}
+	if v := c.Get("ports").StringSlice(); len(v) != 2 || v[0] != "8001" {
+		t.Errorf("StringSlice读取错误...%v", v)
+	}
+	if v := c.Get("ratios").FloatSlice(); len(v) != 2 || v[1] != 1.5 {
+		t.Errorf("FloatSlice读取错误...%v", v)
+	}
+	if v := c.Get("flags").BoolSlice(); len(v) != 2 || !v[0] || v[1] {
+		t.Errorf("BoolSlice读取错误...%v", v)
+	}
+	if v := c.Get("hosts").StringSlice(); len(v) != 2 || v[1] != "omega" {
+		t.Errorf("逗号分隔的字符串读取错误...%v", v)
+	}
+	if _, err := c.Get("hosts").IntSliceE(); err == nil {
+		t.Error("有元素无法转换时应返回错误...")
+	}
+	if v := c.Get("labels").StringMap(); len(v) != 2 || v["team"] != "web" {
+		t.Errorf("StringMap读取错误...%v", v)
+	}
+	if v := c.StringMap("table"); len(v) != 2 || v["b"] != "2" {
+		t.Errorf("ConfigObject.StringMap读取错误...%v", v)
+	}
+	if v := c.Get("slave").SliceMap(); len(v) != 1 {
+		t.Errorf("SliceMap读取错误...%v", v)
+	}
+	// json备份中的表数组解码为[]interface{}
+	r := genResult([]interface{}{map[string]interface{}{"addr": "localhost:6379"}, map[string]interface{}{"addr": "localhost:6380"}})
+	if v := r.SliceMap(); len(v) != 2 || v[1]["addr"] != "localhost:6380" {
+		t.Errorf("SliceMap读取[]interface{}错误...%v", v)
+	}
+}
+
+func TestNumericConversion(t *testing.T) {
+	// toml 整数为int64，浮点数为float64
+	c := newTestObject(t, `
+	int = 3
+	float = 1.5
+	whole = 2.0
+	negative = -1
+	`)
+	if c.Get("int").Float() != 3 || !c.Get("int").Bool() || c.Get("int").Uint() != 3 {
+		t.Error("toml整数转换错误...")
+	}
+	if c.Get("whole").Int() != 2 || c.Get("float").Float() != 1.5 {
+		t.Error("toml浮点数转换错误...")
+	}
+	if _, err := c.Get("float").IntE(); err == nil {
+		t.Error("非整数浮点数转整数应返回错误...")
+	}
+	if _, err := c.Get("negative").UintE(); err == nil || c.Get("negative").Uint() != 0 {
+		t.Error("负数转无符号数应返回错误...")
+	}
+	for _, v := range []interface{}{int8(7), int16(7), int32(7), uint(7), uint8(7), uint16(7), uint32(7), uint64(7), float32(7)} {
+		r := genResult(v)
+		if r.Int() != 7 || r.Uint() != 7 || r.Float() != 7 || !r.Bool() {
+			t.Errorf("%T 类型数值转换错误...", v)
+		}
+	}
+	big := genResult(uint64(math.MaxUint64))
+	if _, err := big.IntE(); err == nil || big.Uint() != math.MaxUint64 {
+		t.Error("uint64溢出检测错误...")
+	}
+	huge := genResult(float64(1 << 64))
+	if _, err := huge.UintE(); err == nil {
+		t.Error("浮点数溢出检测错误...")
+	}
+
+	// json 备份中的数值均为float64
+	cl, err := setEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cl.backups("numeric-test.1.0", map[string]interface{}{"int": 3, "float": 1.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := cl.backupRecovery("numeric-test.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err = cl.genConfigObject("numeric-test.1.0", SourceBackups, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Get("int").Int() != 3 || c.Get("int").Uint() != 3 || c.Get("float").Float() != 1.5 {
+		t.Error("json备份数值转换错误...")
+	}
+
+	// 配置中心http返回的数值同样为float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"config":{"key":"port","value":8080}},{"config":{"key":"ratio","value":0.5}}]`))
+	}))
+	defer server.Close()
+	err = createXdiamondHTTPConf(cl, strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err = cl.LoadConfig("numeric-http.1.0", SourceXdaHTTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Get("port").Int() != 8080 || c.Get("port").Uint() != 8080 || c.Get("ratio").Float() != 0.5 || !c.Get("ratio").Bool() {
+		t.Error("配置中心http数值转换错误...")
+	}
+}
+
+// 创建指向指定http地址的配置中心配置文件
+func createXdiamondHTTPConf(cl *Client, addr string) error {
+	confBody := `
+	group_id = "web"
+	secret_key ="68bq57jhxmi"
+	http_address ="` + addr + `"`
+	return ioutil.WriteFile(cl.env.confDir+"comm/xdiamond.toml", []byte(confBody), 0664)
+}
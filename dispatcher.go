@@ -0,0 +1,285 @@
+package conf
+
+import (
+	"context"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	// 默认单个回调函数超时时间
+	defaultCallbackTimeout = 10 * time.Second
+	// 每个配置标志待回调事件的缓冲大小，超过时合并配置变更事件
+	dispatchBuffer = 64
+)
+
+// 回调事件
+type event struct {
+	fileName string
+	co       *ConfigObject
+	cs       *ChangeSet
+	// 事件发生时的配置键订阅，之后才订阅的不通知
+	subs []*subscription
+	// 配置更新失败时的错误，不为nil时只调用配置更新失败回调函数
+	err error
+}
+
+// 同一配置标志待回调的事件
+type eventQueue struct {
+	events []*event
+	// 有新的事件时通知分发协程
+	wake chan struct{}
+}
+
+// 回调分发，每个配置标志一个分发协程，同一配置标志的事件按发生顺序回调，不同配置标志之间互不阻塞
+type dispatcher struct {
+	mutex          sync.RWMutex
+	callbacks      []CallbackHandel
+	changeHandlers []ChangeHandler
+	errorHandlers  []ErrorHandler
+	queues         map[string]*eventQueue
+	// 单个回调函数超时时间，超时之后继续调用同一事件的其他回调函数，下一个事件等待其执行完毕
+	timeout time.Duration
+	log     *loger
+	ctx     context.Context
+}
+
+// 实例化回调分发
+func newDispatcher(ctx context.Context, log *loger, timeout time.Duration) *dispatcher {
+	return &dispatcher{
+		callbacks:      make([]CallbackHandel, 0),
+		changeHandlers: make([]ChangeHandler, 0),
+		errorHandlers:  make([]ErrorHandler, 0),
+		queues:         make(map[string]*eventQueue),
+		timeout:        timeout,
+		log:            log,
+		ctx:            ctx,
+	}
+}
+
+// 添加回调函数
+func (d *dispatcher) addCallback(handel CallbackHandel) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.callbacks = append(d.callbacks, handel)
+}
+
+// 删除回调函数
+func (d *dispatcher) removeCallback(handel CallbackHandel) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	callbacks := make([]CallbackHandel, 0, len(d.callbacks))
+	for _, cb := range d.callbacks {
+		if !sameHandler(cb, handel) {
+			callbacks = append(callbacks, cb)
+		}
+	}
+	d.callbacks = callbacks
+}
+
+// 添加配置变更回调函数
+func (d *dispatcher) addChangeHandler(handel ChangeHandler) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.changeHandlers = append(d.changeHandlers, handel)
+}
+
+// 删除配置变更回调函数
+func (d *dispatcher) removeChangeHandler(handel ChangeHandler) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	handlers := make([]ChangeHandler, 0, len(d.changeHandlers))
+	for _, h := range d.changeHandlers {
+		if !sameHandler(h, handel) {
+			handlers = append(handlers, h)
+		}
+	}
+	d.changeHandlers = handlers
+}
+
+// 添加配置更新失败回调函数
+func (d *dispatcher) addErrorHandler(handel ErrorHandler) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.errorHandlers = append(d.errorHandlers, handel)
+}
+
+// 删除配置更新失败回调函数
+func (d *dispatcher) removeErrorHandler(handel ErrorHandler) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	handlers := make([]ErrorHandler, 0, len(d.errorHandlers))
+	for _, h := range d.errorHandlers {
+		if !sameHandler(h, handel) {
+			handlers = append(handlers, h)
+		}
+	}
+	d.errorHandlers = handlers
+}
+
+// 判断是否为同一个回调函数，不可比较的类型(比如函数)视为不同
+func sameHandler(a interface{}, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// 投递回调事件，不阻塞配置同步，客户端关闭之后丢弃;
+// 待回调事件达到缓冲大小时丢弃配置更新失败事件，并将配置变更合并为最新的配置
+func (d *dispatcher) dispatch(e *event) {
+	if d.ctx.Err() != nil {
+		return
+	}
+	d.mutex.Lock()
+	q, ok := d.queues[e.fileName]
+	if !ok {
+		q = &eventQueue{wake: make(chan struct{}, 1)}
+		d.queues[e.fileName] = q
+		go d.run(q)
+	}
+	if len(q.events) >= dispatchBuffer {
+		e = d.coalesce(q, e)
+	}
+	if e != nil {
+		q.events = append(q.events, e)
+	}
+	d.mutex.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 合并待回调事件，返回需要追加的事件，没有需要追加的事件时返回nil
+func (d *dispatcher) coalesce(q *eventQueue, e *event) *event {
+	events := make([]*event, 0, len(q.events))
+	for _, p := range q.events {
+		if p.err != nil {
+			d.log.Warning("配置", p.fileName, "待回调事件过多,丢弃配置更新失败回调:", p.err)
+			continue
+		}
+		events = append(events, p)
+	}
+	defer func() {
+		q.events = events
+	}()
+	if e.err != nil {
+		d.log.Warning("配置", e.fileName, "待回调事件过多,丢弃配置更新失败回调:", e.err)
+		return nil
+	}
+	if len(events) < dispatchBuffer {
+		return e
+	}
+	// 与最后一个待回调的配置变更合并，变更内容以合并之前的配置比对
+	last := events[len(events)-1]
+	events = events[:len(events)-1]
+	d.log.Warning("配置", e.fileName, "待回调事件过多,合并为最新配置回调")
+	cs := diff(last.cs.Old, e.co)
+	if cs.Old != nil && cs.Empty() {
+		return nil
+	}
+	return &event{fileName: e.fileName, co: e.co, cs: cs, subs: e.subs}
+}
+
+// 按顺序处理同一配置标志的回调事件
+func (d *dispatcher) run(q *eventQueue) {
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-q.wake:
+		}
+		for d.ctx.Err() == nil {
+			d.mutex.Lock()
+			if len(q.events) == 0 {
+				d.mutex.Unlock()
+				break
+			}
+			e := q.events[0]
+			q.events = q.events[1:]
+			d.mutex.Unlock()
+			d.handle(e)
+		}
+	}
+}
+
+// 依次调用全部回调函数以及配置键订阅，配置更新失败时只调用配置更新失败回调函数;
+// 超时的回调函数执行完毕之后才返回，同一回调函数不会与同一配置标志的下一个事件并发执行
+func (d *dispatcher) handle(e *event) {
+	d.mutex.RLock()
+	callbacks := append([]CallbackHandel(nil), d.callbacks...)
+	handlers := append([]ChangeHandler(nil), d.changeHandlers...)
+	errorHandlers := append([]ErrorHandler(nil), d.errorHandlers...)
+	d.mutex.RUnlock()
+	running := make([]<-chan struct{}, 0)
+	call := func(fileName string, fn func()) {
+		done := d.call(fileName, fn)
+		if done != nil {
+			running = append(running, done)
+		}
+	}
+	defer func() {
+		for _, done := range running {
+			select {
+			case <-done:
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}()
+	if e.err != nil {
+		for _, h := range errorHandlers {
+			h := h
+			call(e.fileName, func() {
+				h.ErrorHandel(e.fileName, e.err)
+			})
+		}
+		return
+	}
+	for _, cb := range callbacks {
+		cb := cb
+		call(e.fileName, func() {
+			cb.CallbackHandel(e.fileName, e.co)
+		})
+	}
+	for _, h := range handlers {
+		h := h
+		call(e.fileName, func() {
+			h.ChangeHandel(e.fileName, e.cs)
+		})
+	}
+	notify(e.subs, e.fileName, e.cs, call)
+}
+
+// 调用回调函数，回调函数panic时记录日志;超时之后不再等待并返回执行完毕时关闭的通道，超时的回调函数继续执行
+func (d *dispatcher) call(fileName string, fn func()) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				d.log.Error("配置", fileName, "回调函数panic:", r, string(debug.Stack()))
+			}
+		}()
+		fn()
+	}()
+	if d.timeout <= 0 {
+		<-done
+		return nil
+	}
+	timer := time.NewTimer(d.timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		d.log.Warning("配置", fileName, "回调函数执行超过", d.timeout, ",继续调用其他回调函数,下一次回调之前等待其执行完毕...")
+		return done
+	}
+}
@@ -0,0 +1,135 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// dotenv 配置文件格式，配置标志 comm.docker 对应 comm/docker.env，配置标志为目录时对应目录下的 .env(与 docker-compose 一致)
+var dotenvFormats = []fileFormat{
+	{".env", decodeDotenv},
+	{"/.env", decodeDotenv},
+}
+
+// 实例化 dotenv 配置文件解析
+func newDotenvFile(e *env) *localFile {
+	return &localFile{env: e, source: SourceDotenv, formats: dotenvFormats}
+}
+
+// 解析 KEY=VALUE 格式的 dotenv 文件，配置键 DB__MASTER__ADDR 对应 db.master.addr;
+// 支持 export 前缀、# 注释、单双引号(可以跨行，单引号内不转义不展开)以及 ${VAR}、${VAR:-default} 展开，
+// 展开时优先使用文件中之前定义的变量，其次为环境变量，未定义时为空;没有引号的配置值按toml配置值推断类型
+func decodeDotenv(content []byte) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	vars := make(map[string]string)
+	lines := strings.Split(strings.Replace(string(content), "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimSpace(line[len("export "):])
+		}
+		n := strings.Index(line, "=")
+		if n <= 0 {
+			return nil, fmt.Errorf("第%d行:缺少\"=\":%s", i+1, line)
+		}
+		name := strings.TrimSpace(line[:n])
+		raw := strings.TrimSpace(line[n+1:])
+		start := i + 1
+		var value interface{}
+		if raw != "" && (raw[0] == '"' || raw[0] == '\'') {
+			quote := raw[0]
+			raw = raw[1:]
+			// 引号内的配置值可以跨行，直到遇到闭合的引号
+			for closing(raw, quote) == -1 {
+				if i+1 == len(lines) {
+					return nil, fmt.Errorf("第%d行:引号没有闭合", start)
+				}
+				i++
+				raw += "\n" + lines[i]
+			}
+			s := raw[:closing(raw, quote)]
+			if quote == '"' {
+				s = expandVars(s, vars, true)
+			}
+			vars[name] = s
+			value = s
+		} else {
+			if n := strings.Index(raw, " #"); n != -1 {
+				raw = strings.TrimSpace(raw[:n])
+			}
+			s := expandVars(raw, vars, false)
+			vars[name] = s
+			value = InferValue(s)
+		}
+		data[strings.ToLower(strings.Replace(name, envSeparator, ".", -1))] = value
+	}
+	return data, nil
+}
+
+// 获取闭合引号的位置，双引号内可以以反斜杠转义
+func closing(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if quote == '"' && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// 双引号内的转义字符
+var dotenvEscapes = map[byte]byte{'n': '\n', 'r': '\r', 't': '\t', '"': '"', '\\': '\\', '$': '$'}
+
+// 展开 ${VAR} 以及 ${VAR:-default}，quoted 为true时(双引号内)同时处理转义字符，\$ 表示 $ 本身，
+// 否则 $$ 表示 $ 本身
+func expandVars(s string, vars map[string]string, quoted bool) string {
+	if !strings.ContainsAny(s, "$\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if quoted && s[i] == '\\' && i+1 < len(s) {
+			if c, ok := dotenvEscapes[s[i+1]]; ok {
+				b.WriteByte(c)
+				i++
+				continue
+			}
+		}
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		if !quoted && s[i+1] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		end := strings.Index(s[i:], "}")
+		if s[i+1] != '{' || end == -1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		name := s[i+2 : i+end]
+		def := ""
+		if n := strings.Index(name, ":-"); n != -1 {
+			name, def = name[:n], name[n+2:]
+		}
+		v, ok := vars[name]
+		if !ok {
+			v, ok = os.LookupEnv(name)
+		}
+		if !ok || v == "" {
+			v = def
+		}
+		b.WriteString(v)
+		i += end
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package conf
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDotenv(t *testing.T) {
+	os.Setenv("WEB_GO_CONFIG_TEST_USER", "web")
+	defer os.Unsetenv("WEB_GO_CONFIG_TEST_USER")
+	cl := newTestClient(t, WithInterpolation())
+	writeTestFile(t, cl, "comm/docker.env", `# 注释
+export DB__MASTER__HOST=10.0.0.1
+DB__MASTER__PORT=3306
+DB__MASTER__ADDR=${DB__MASTER__HOST}:${DB__MASTER__PORT} # 行尾注释
+DB__MASTER__USER=${WEB_GO_CONFIG_TEST_USER}
+DB__MASTER__PASSWORD=${WEB_GO_CONFIG_TEST_NONE:-secret}
+DEBUG=true
+TITLE="hello\tworld ${DB__MASTER__HOST}"
+RAW='${DB__MASTER__HOST}\n'
+PRICE="5"
+CERT="-----BEGIN-----
+abc
+-----END-----"
+`)
+	c, err := cl.LoadConfig("comm.docker", SourceDotenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Get("db.master.addr").String() != "10.0.0.1:3306" || c.Get("db.master.port").Value() != int64(3306) || !c.Get("debug").Bool() {
+		t.Errorf("dotenv 解析错误...%v", c.All())
+	}
+	if c.Get("db.master.user").String() != "web" || c.Get("db.master.password").String() != "secret" {
+		t.Errorf("dotenv 变量展开错误...%v", c.All())
+	}
+	if c.Get("title").String() != "hello\tworld 10.0.0.1" || c.Get("raw").String() != `${DB__MASTER__HOST}\n` || c.Get("price").Value() != "5" {
+		t.Errorf("dotenv 引号处理错误...%v", c.All())
+	}
+	if c.Get("cert").String() != "-----BEGIN-----\nabc\n-----END-----" {
+		t.Errorf("dotenv 多行配置值错误...%q", c.Get("cert").String())
+	}
+	// 配置标志为目录时读取目录下的 .env
+	writeTestFile(t, cl, "comm/.env", "NAME=compose")
+	c, err = cl.LoadConfig("comm", SourceDotenv)
+	if err != nil || c.Get("name").String() != "compose" {
+		t.Errorf("目录下的 .env 读取错误...%v", err)
+	}
+	data, err := decodeDotenv([]byte(`A=1
+COST="\$${A} $$ \\$A"
+PLAIN=$$HOME`))
+	if err != nil || data["cost"] != `$1 $$ \$A` || data["plain"] != "$HOME" {
+		t.Errorf("dotenv $ 转义错误...%v %v", data, err)
+	}
+	_, err = decodeDotenv([]byte("A=\"abc\nB=1"))
+	if err == nil || err.Error() != "第1行:引号没有闭合" {
+		t.Errorf("引号没有闭合时应返回错误...%v", err)
+	}
+}
@@ -4,13 +4,20 @@ import (
 	"errors"
 	"os"
 	"runtime"
+	"sort"
 	"strings"
+
+	"github.com/BurntSushi/toml"
 )
 
 const (
 	// envConf 定义配置环境比如 dev test product
 	envConf       = "WEB_GO_CONFIG_ENV"
 	envConfigPath = "WEB_GO_CONFIG_PATH"
+	// envOverridePrefix 环境变量覆盖配置的默认前缀
+	envOverridePrefix = "WEB_GO_CONFIG"
+	// envSeparator 环境变量名中配置标志以及配置键各级之间的分隔符
+	envSeparator = "__"
 )
 
 // 环境定义
@@ -21,12 +28,15 @@ type env struct {
 	env string
 }
 
-// newEnv 初始化基本环境信息
-func newEnv() (*env, error) {
+// newEnv 初始化基本环境信息，配置路径和配置环境为空时从环境变量读取
+func newEnv(confPath string, envName string) (*env, error) {
 	v := new(env)
-	v.env = getEnv()
+	v.env = envName
+	if v.env == "" {
+		v.env = getEnv()
+	}
 	var err error
-	v.confDir, err = getConfigDir()
+	v.confDir, err = getConfigDir(confPath, v.env)
 	if err != nil {
 		return nil, err
 	}
@@ -43,8 +53,10 @@ func getEnv() string {
 }
 
 // getConfigDir 获取配置路径
-func getConfigDir() (string, error) {
-	confDir := os.Getenv(envConfigPath)
+func getConfigDir(confDir string, envName string) (string, error) {
+	if confDir == "" {
+		confDir = os.Getenv(envConfigPath)
+	}
 	if confDir == "" {
 		if runtime.GOOS == "windows" {
 			confDir = "C:/web_go_config"
@@ -63,6 +75,53 @@ func getConfigDir() (string, error) {
 	if !dirInfo.IsDir() {
 		return "", errors.New(confDir + ":不是一个有效的目录")
 	}
-	confDir = confDir + getEnv() + "/"
+	confDir = confDir + envName + "/"
 	return confDir, nil
 }
+
+// 以环境变量覆盖配置，返回被覆盖的配置键，比如 WEB_GO_CONFIG__COMM_APP__BASE__INT=5 覆盖 comm.app 的 base.int，
+// 配置标志中的"."以"_"代替，配置键各级之间以"__"分隔且不区分大小写，配置中不存在的配置键直接添加;
+// 此映射不可逆，comm.app 与 comm_app 使用同一环境变量，含有"-"等字符的配置键无法覆盖
+func (cl *Client) envOverride(fileName string, kvMap map[string]Result) []string {
+	overridden := make([]string, 0)
+	if cl.envPrefix == "" || fileName == "" {
+		return overridden
+	}
+	prefix := strings.ToUpper(cl.envPrefix + envSeparator + strings.Replace(fileName, ".", "_", -1) + envSeparator)
+	keys := make(map[string]string)
+	for k := range kvMap {
+		keys[strings.ToLower(k)] = k
+	}
+	for _, kv := range os.Environ() {
+		i := strings.Index(kv, "=")
+		if i <= 0 || !strings.HasPrefix(strings.ToUpper(kv[:i]), prefix) {
+			continue
+		}
+		key := strings.ToLower(strings.Replace(kv[len(prefix):i], envSeparator, ".", -1))
+		if key == "" {
+			continue
+		}
+		if k, ok := keys[key]; ok {
+			key = k
+		}
+		kvMap[key] = genResult(InferValue(kv[i+1:]))
+		overridden = append(overridden, key)
+	}
+	sort.Strings(overridden)
+	return overridden
+}
+
+// InferValue 推断字符串配置值的类型，按toml配置值解析，比如 5 为整数、1.5 为浮点数、true 为布尔值、[1, 2] 为数组，无法解析时为字符串;
+// 环境变量覆盖、.properties、.ini、dotenv 以及只有字符串配置值的第三方配置源(比如 etcd)均以此推断类型
+func InferValue(s string) interface{} {
+	v := strings.TrimSpace(s)
+	if v == "" || strings.ContainsAny(v, "\r\n") {
+		return s
+	}
+	var tmp map[string]interface{}
+	_, err := toml.Decode("v = "+v, &tmp)
+	if err != nil {
+		return s
+	}
+	return tmp["v"]
+}
@@ -0,0 +1,69 @@
+package conf
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEnvOverride(t *testing.T) {
+	cl := newTestClient(t)
+	writeTestFile(t, cl, "comm/env.toml", "[base]\nint = 1\nMaxConn = 10\ntitle = \"web\"")
+	envs := map[string]string{
+		"WEB_GO_CONFIG__COMM_ENV__BASE__INT":     "5",
+		"WEB_GO_CONFIG__COMM_ENV__BASE__MAXCONN": "20",
+		"WEB_GO_CONFIG__COMM_ENV__BASE__RATE":    "0.5",
+		"WEB_GO_CONFIG__COMM_ENV__DEBUG":         "true",
+		"WEB_GO_CONFIG__COMM_ENV__HOSTS":         `["a", "b"]`,
+		"WEB_GO_CONFIG__COMM_ENV__NAME":          "web app",
+		"WEB_GO_CONFIG__COMM_APP__BASE__TITLE":   "other",
+	}
+	for k, v := range envs {
+		os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range envs {
+			os.Unsetenv(k)
+		}
+	}()
+	c, err := cl.LoadConfig("comm.env", SourceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Get("base.int").Value() != int64(5) || c.Get("base.MaxConn").Int() != 20 || c.Get("base.title").String() != "web" {
+		t.Errorf("环境变量覆盖配置错误...%v", c.All())
+	}
+	if c.Get("base.rate").Float() != 0.5 || !c.Get("debug").Bool() || len(c.Get("hosts").StringSlice()) != 2 || c.Get("name").String() != "web app" {
+		t.Errorf("环境变量配置值类型推断错误...%v", c.All())
+	}
+	if o := c.Overridden(); len(o) != 6 || o[0] != "base.MaxConn" || o[5] != "name" {
+		t.Errorf("被覆盖的配置键错误...%v", o)
+	}
+	if o := c.Sub("base").Overridden(); len(o) != 3 || o[0] != "MaxConn" {
+		t.Errorf("配置子集被覆盖的配置键错误...%v", o)
+	}
+	cl = newTestClient(t, WithEnvPrefix(""))
+	writeTestFile(t, cl, "comm/env.toml", "[base]\nint = 1")
+	c, err = cl.LoadConfig("comm.env", SourceFile)
+	if err != nil || c.Get("base.int").Int() != 1 || len(c.Overridden()) != 0 {
+		t.Errorf("前缀为空时不应以环境变量覆盖配置...%v", err)
+	}
+}
+
+func TestInferValue(t *testing.T) {
+	ti, _ := time.Parse(time.RFC3339, "2018-05-27T07:32:00Z")
+	cases := map[string]interface{}{
+		"5":                    int64(5),
+		"-1.5":                 -1.5,
+		"false":                false,
+		"2018-05-27T07:32:00Z": ti,
+		"hello":                "hello",
+		`"5"`:                  "5",
+		"":                     "",
+	}
+	for s, v := range cases {
+		if r := InferValue(s); r != v {
+			t.Errorf("%q 类型推断错误...%v(%T)", s, r, r)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 错误类别，可通过 errors.Is(err, ErrXxx) 判断加载失败的原因
+var (
+	// ErrNotFound 配置不存在，比如配置文件不存在或者未指定配置标志
+	ErrNotFound = errors.New("配置不存在")
+	// ErrParse 配置内容无法解析
+	ErrParse = errors.New("配置解析失败")
+	// ErrSource 配置源不可用，比如配置中心连接失败或配置中心基础配置错误
+	ErrSource = errors.New("配置源不可用")
+	// ErrBackupRecovery 配置中心不可用且从本地备份恢复失败
+	ErrBackupRecovery = errors.New("备份恢复失败")
+	// ErrUnsupportedSource 不受支持的配置来源
+	ErrUnsupportedSource = errors.New("不受支持的配置来源")
+	// ErrValidation 配置校验不通过
+	ErrValidation = errors.New("配置校验不通过")
+)
+
+// Error 配置加载错误
+type Error struct {
+	// Kind 错误类别，为以上 ErrXxx 之一
+	Kind error
+	// FileName 配置标志
+	FileName string
+	// Source 配置来源
+	Source Source
+	// Err 原始错误
+	Err error
+}
+
+// 实例化一个配置加载错误
+func newError(kind error, fileName string, source Source, err error) *Error {
+	return &Error{Kind: kind, FileName: fileName, Source: source, Err: err}
+}
+
+// Error 实现error接口
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("配置[%s]%s", e.FileName, e.Kind.Error())
+	if e.Err != nil {
+		msg += ":" + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 判断错误类别
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// ConvertError 配置值类型转换错误
+type ConvertError struct {
+	// Key 配置键，无法确定时为空
+	Key string
+	// Value 原配置值
+	Value interface{}
+	// To 目标类型
+	To string
+	// Err 原始错误
+	Err error
+}
+
+// Error 实现error接口
+func (e *ConvertError) Error() string {
+	msg := fmt.Sprintf("配置值%v(%T)无法转换为%s", e.Value, e.Value, e.To)
+	if e.Key != "" {
+		msg = "配置[" + e.Key + "]:" + msg
+	}
+	if e.Err != nil {
+		msg += ":" + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap 返回原始错误
+func (e *ConvertError) Unwrap() error {
+	return e.Err
+}
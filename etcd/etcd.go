@@ -0,0 +1,200 @@
+// Package etcd etcd v3 配置源，通过 conf.RegisterSource 注册之后与内置配置来源一样使用，
+// 前缀下的配置键以"/"分隔层级，比如 /config/dev/comm.app/db/master/addr 为 comm.app 的 db.master.addr
+// 依赖 go.etcd.io/etcd/client/v3 v3.5.17，测试另外依赖 go.etcd.io/etcd/server/v3 并带有 etcd 构建标签
+package etcd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	conf "github.com/tttlkkkl/go-config"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// Name 注册的配置源名称
+	Name = "etcd"
+	// DefaultPrefix 默认配置键前缀，{env} 为配置环境，{fileName} 为配置标志
+	DefaultPrefix = "/config/{env}/{fileName}/"
+	// 默认连接以及请求超时时间
+	defaultTimeout = 5 * time.Second
+	// 变更监听中断之后重新监听的间隔
+	retryInterval = 2 * time.Second
+)
+
+// Config etcd 连接配置
+type Config struct {
+	// Endpoints etcd 地址
+	Endpoints []string
+	// Username Password 认证信息，未开启认证时为空
+	Username string
+	Password string
+	// Timeout 连接以及请求超时时间，为0时为5秒
+	Timeout time.Duration
+	// Prefix 配置键前缀，为空时为 DefaultPrefix
+	Prefix string
+}
+
+// 基础配置文件，见 _examples/dev/comm/etcd.toml
+type fileConfig struct {
+	Endpoints []string `toml:"endpoints"`
+	Username  string   `toml:"username"`
+	Password  string   `toml:"password"`
+	// Timeout 比如 "5s"，数值以秒为单位
+	Timeout interface{} `toml:"timeout"`
+	Prefix  string      `toml:"prefix"`
+}
+
+// ReadConfig 读取 toml 格式的 etcd 基础配置，比如 /var/web_go_config/dev/comm/etcd.toml
+func ReadConfig(file string) (Config, error) {
+	c := Config{}
+	f := new(fileConfig)
+	_, err := toml.DecodeFile(file, f)
+	if err != nil {
+		return c, errors.New("etcd基础配置" + file + "读取失败:" + err.Error())
+	}
+	c.Endpoints, c.Username, c.Password, c.Prefix = f.Endpoints, f.Username, f.Password, f.Prefix
+	switch t := f.Timeout.(type) {
+	case nil:
+	case int64:
+		c.Timeout = time.Duration(t) * time.Second
+	case string:
+		c.Timeout, err = time.ParseDuration(t)
+		if err != nil {
+			return c, errors.New("etcd基础配置timeout错误:" + err.Error())
+		}
+	default:
+		return c, errors.New("etcd基础配置timeout错误:需为时间间隔字符串或者秒数")
+	}
+	return c, nil
+}
+
+// Provider etcd 配置源，实现 conf.Provider、conf.Watcher 以及 conf.EnvProvider，
+// 通过客户端加载时前缀中的 {env} 为客户端的配置环境，同一连接可以被不同配置环境的客户端共用
+type Provider struct {
+	cli *clientv3.Client
+	// 配置环境，只在 ForEnv 返回的配置源中设置
+	env     string
+	prefix  string
+	timeout time.Duration
+}
+
+// New 连接 etcd，不再使用时调用 Close 断开连接
+func New(c Config) (*Provider, error) {
+	if len(c.Endpoints) == 0 {
+		return nil, errors.New("etcd未指定endpoints")
+	}
+	p := &Provider{prefix: c.Prefix, timeout: c.Timeout}
+	if p.prefix == "" {
+		p.prefix = DefaultPrefix
+	}
+	if p.timeout <= 0 {
+		p.timeout = defaultTimeout
+	}
+	var err error
+	p.cli, err = clientv3.New(clientv3.Config{
+		Endpoints:   c.Endpoints,
+		Username:    c.Username,
+		Password:    c.Password,
+		DialTimeout: p.timeout,
+	})
+	if err != nil {
+		return nil, errors.New("etcd连接失败:" + err.Error())
+	}
+	return p, nil
+}
+
+// Register 连接 etcd 并以 Name 注册为配置源，返回的 conf.Source 用于 conf.NewConfig、conf.LoadConfig，
+// 与配置中心一样缓存、备份(etcd 不可用时从本地备份恢复)以及回调
+func Register(c Config) (conf.Source, *Provider, error) {
+	p, err := New(c)
+	if err != nil {
+		return 0, nil, err
+	}
+	return conf.RegisterSource(Name, p), p, nil
+}
+
+// ForEnv 实现 conf.EnvProvider 接口，返回以 env 替换前缀中 {env} 的配置源，与 p 共用同一连接
+func (p *Provider) ForEnv(env string) conf.Provider {
+	return &Provider{cli: p.cli, env: env, prefix: p.prefix, timeout: p.timeout}
+}
+
+// 获取配置标志对应的配置键前缀
+func (p *Provider) keyPrefix(fileName string) string {
+	prefix := strings.NewReplacer("{env}", p.env, "{fileName}", fileName).Replace(p.prefix)
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
+
+// Load 读取前缀下的全部配置键，配置值按toml配置值推断类型，直接调用时需先通过 ForEnv 指定配置环境
+func (p *Provider) Load(fileName string) (map[string]interface{}, error) {
+	data, _, err := p.load(context.Background(), fileName)
+	return data, err
+}
+
+// 读取前缀下的全部配置键，同时返回读取时的版本号
+func (p *Provider) load(ctx context.Context, fileName string) (map[string]interface{}, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	prefix := p.keyPrefix(fileName)
+	resp, err := p.cli.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, 0, errors.New(prefix + "下没有配置")
+	}
+	data := make(map[string]interface{})
+	for _, kv := range resp.Kvs {
+		key := strings.Trim(strings.TrimPrefix(string(kv.Key), prefix), "/")
+		if key == "" {
+			continue
+		}
+		data[strings.Replace(key, "/", ".", -1)] = conf.InferValue(string(kv.Value))
+	}
+	return data, resp.Header.Revision, nil
+}
+
+// Watch 以 etcd watch 监听前缀下的配置变更，有变更时重新读取全部配置键;监听中断(比如 etcd 不可用)之后定时重试，
+// 重新监听之前先同步一次配置，etcd 恢复之后更新为最新配置
+func (p *Provider) Watch(ctx context.Context, fileName string, update func(map[string]interface{}, error)) error {
+	go func() {
+		for {
+			data, rev, err := p.load(ctx, fileName)
+			if err == nil {
+				update(data, nil)
+				p.watch(ctx, fileName, rev, update)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryInterval):
+			}
+		}
+	}()
+	return nil
+}
+
+// 从指定版本之后开始监听，监听中断时返回
+func (p *Provider) watch(ctx context.Context, fileName string, rev int64, update func(map[string]interface{}, error)) {
+	wch := p.cli.Watch(clientv3.WithRequireLeader(ctx), p.keyPrefix(fileName), clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+	for resp := range wch {
+		err := resp.Err()
+		if err != nil {
+			update(nil, err)
+			return
+		}
+		data, _, err := p.load(ctx, fileName)
+		update(data, err)
+	}
+}
+
+// Close 断开连接，ForEnv 返回的配置源共用此连接
+func (p *Provider) Close() error {
+	return p.cli.Close()
+}
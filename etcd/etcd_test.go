@@ -0,0 +1,170 @@
+//go:build etcd
+// +build etcd
+
+// 测试依赖内嵌 etcd 服务(go.etcd.io/etcd/server/v3)，带有 etcd 构建标签，需通过 go test -tags etcd ./etcd/ 运行
+
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	conf "github.com/tttlkkkl/go-config"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+//以通道接收回调
+type chanCallback chan *conf.ConfigObject
+
+func (c chanCallback) CallbackHandel(fileName string, co *conf.ConfigObject) {
+	c <- co
+}
+
+// 获取一个空闲端口
+func freeURL(t *testing.T) url.URL {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return url.URL{Scheme: "http", Host: l.Addr().String()}
+}
+
+// 启动内嵌的 etcd 服务
+func startEtcd(t *testing.T) (*embed.Etcd, string) {
+	dir, err := ioutil.TempDir("", "web_go_config_etcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	cfg := embed.NewConfig()
+	cfg.Dir = dir
+	cfg.LogLevel = "error"
+	clientURL, peerURL := freeURL(t), freeURL(t)
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{clientURL}, []url.URL{clientURL}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{peerURL}, []url.URL{peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		e.Close()
+		t.Fatal("etcd 启动超时...")
+	}
+	return e, clientURL.Host
+}
+
+// 实例化配置客户端，配置目录为临时目录
+func newClient(t *testing.T, path string, opts ...conf.Option) *conf.Client {
+	opts = append([]conf.Option{conf.WithConfigPath(path), conf.WithEnv("dev"), conf.WithoutLogFile(), conf.WithLogOutput(ioutil.Discard, conf.All)}, opts...)
+	cl, err := conf.New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = cl.Close()
+	})
+	return cl
+}
+
+func TestEtcd(t *testing.T) {
+	server, endpoint := startEtcd(t)
+	stopped := false
+	defer func() {
+		if !stopped {
+			server.Close()
+		}
+	}()
+	path, err := ioutil.TempDir("", "web_go_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	for _, dir := range []string{"/dev/comm", "/test/comm"} {
+		err = os.MkdirAll(path+dir, 0775)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := filepath.Join(path, "dev/comm/etcd.toml")
+	err = ioutil.WriteFile(file, []byte(fmt.Sprintf("endpoints = [%q]\ntimeout = \"2s\"", endpoint)), 0664)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadConfig(file)
+	if err != nil || c.Timeout != 2*time.Second {
+		t.Fatalf("etcd 基础配置读取错误...%+v %v", c, err)
+	}
+	source, p, err := Register(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if source.String() != Name {
+		t.Errorf("配置源名称错误...%v", source)
+	}
+	kv, err := clientv3.New(clientv3.Config{Endpoints: []string{endpoint}, DialTimeout: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kv.Close()
+	ctx := context.Background()
+	for k, v := range map[string]string{
+		"/config/dev/comm.app/db/master/addr": "10.0.0.1:3306",
+		"/config/dev/comm.app/db/master/port": "3306",
+		"/config/dev/comm.app/debug":          "true",
+		"/config/test/comm.app/debug":         "false",
+	} {
+		_, err = kv.Put(ctx, k, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cb := make(chanCallback, 10)
+	cl := newClient(t, path, conf.WithCallback(cb))
+	co, err := cl.LoadConfig("comm.app", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if co.Get("db.master.addr").String() != "10.0.0.1:3306" || co.Get("db.master.port").Value() != int64(3306) || !co.Get("debug").Bool() {
+		t.Errorf("etcd 配置读取错误...%v", co.All())
+	}
+	// 同一配置源以客户端各自的配置环境读取
+	co, err = newClient(t, path, conf.WithEnv("test")).LoadConfig("comm.app", source)
+	if err != nil || co.Get("debug").Value() != false || co.Get("db.master.addr").Exists() {
+		t.Errorf("etcd 应以客户端的配置环境读取...%v", err)
+	}
+	<-cb
+	_, err = kv.Put(ctx, "/config/dev/comm.app/db/master/port", "3307")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case co := <-cb:
+		if co.Get("db.master.port").Int() != 3307 {
+			t.Errorf("etcd 配置变更错误...%v", co.All())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("etcd 配置变更之后没有回调...")
+	}
+	// etcd 不可用时从本地备份恢复
+	server.Close()
+	stopped = true
+	co, err = newClient(t, path).LoadConfig("comm.app", source)
+	if err != nil || co.Get("db.master.port").Int() != 3307 {
+		t.Errorf("etcd 不可用时应从本地备份恢复...%v", err)
+	}
+}
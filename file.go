@@ -2,34 +2,135 @@ package conf
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
-
-	"github.com/BurntSushi/toml"
 )
 
+// 配置文件中指定包含文件的配置键
+const includeKey = "@include"
+
 type localFile struct {
+	env *env
+	// 最近一次解析涉及的文件，用于监听文件变更
+	files []string
+	// 配置来源
+	source Source
+	// 支持的配置文件格式，按顺序查找
+	formats []fileFormat
 }
 
-func newLocalFile() *localFile {
-	return new(localFile)
+func newLocalFile(e *env) *localFile {
+	return &localFile{env: e, source: SourceFile, formats: formats}
 }
 
 // 解析本地配置文件
-func (l *localFile) analysisConfig(fileName string) (map[string]interface{}, error) {
-	var data = make(map[string]interface{})
-	var err error
-	fileName, err = l.getFullFileName(fileName)
+func (l *localFile) Load(fileName string) (map[string]interface{}, error) {
+	fullFileName, err := l.getFullFileName(fileName)
+	if err != nil {
+		return nil, newError(ErrNotFound, fileName, l.source, err)
+	}
+	_, err = os.Stat(fullFileName)
 	if err != nil {
-		return nil, err
+		if os.IsNotExist(err) {
+			return nil, newError(ErrNotFound, fileName, l.source, err)
+		}
+		return nil, newError(ErrParse, fileName, l.source, err)
 	}
-	_, err = toml.DecodeFile(fileName, &data)
+	files := make([]string, 0, 1)
+	data, err := l.decodeFile(fullFileName, nil, &files)
 	if err != nil {
-		return nil, errors.New("配置文件" + fileName + "解析失败:" + err.Error())
+		return nil, newError(ErrParse, fileName, l.source, err)
 	}
+	l.files = files
 	return data, nil
 }
 
-// 获取配置文件全名
+// 解析配置文件，配置文件中的 "@include" 指定需要包含的文件，比如 "@include" = ["comm.base", "../base/comm/app.toml"]，
+// 以".toml"、".yaml"等配置文件后缀结尾的为相对于配置环境目录的路径，否则与配置标志一致;按顺序合并包含的文件，当前文件的配置优先，表按配置键递归合并
+func (l *localFile) decodeFile(name string, stack []string, files *[]string) (map[string]interface{}, error) {
+	for _, f := range stack {
+		if filepath.Clean(f) == filepath.Clean(name) {
+			return nil, errors.New("配置文件循环包含:" + strings.Join(append(stack, name), " -> "))
+		}
+	}
+	*files = append(*files, name)
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, errors.New("配置文件" + name + "读取失败:" + err.Error())
+	}
+	data, err := formatOf(l.formats, name).decode(content)
+	if err != nil {
+		return nil, errors.New("配置文件" + name + "解析失败:" + err.Error())
+	}
+	v, ok := data[includeKey]
+	if !ok {
+		return data, nil
+	}
+	delete(data, includeKey)
+	line := includeLine(string(content))
+	includes, ok := v.([]interface{})
+	if s, isString := v.(string); isString {
+		includes, ok = []interface{}{s}, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("配置文件%s第%d行:%s 必须为字符串数组", name, line, includeKey)
+	}
+	merged := make(map[string]interface{})
+	for _, item := range includes {
+		include, ok := item.(string)
+		if !ok || include == "" {
+			return nil, fmt.Errorf("配置文件%s第%d行:%s 必须为字符串数组", name, line, includeKey)
+		}
+		tmp, err := l.decodeFile(l.getIncludeFileName(include), append(stack, name), files)
+		if err != nil {
+			return nil, fmt.Errorf("配置文件%s第%d行包含%s失败:%v", name, line, include, err)
+		}
+		mergeMap(merged, tmp)
+	}
+	mergeMap(merged, data)
+	return merged, nil
+}
+
+// 获取包含的文件全名
+func (l localFile) getIncludeFileName(include string) string {
+	if isFormatExt(include) {
+		if filepath.IsAbs(include) {
+			return filepath.ToSlash(filepath.Clean(include))
+		}
+		return filepath.ToSlash(filepath.Clean(l.env.confDir + include))
+	}
+	name, _ := l.getFullFileName(include)
+	return name
+}
+
+// 获取 "@include" 所在行号，用于错误信息
+func includeLine(content string) int {
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, `"`+includeKey+`"`) || strings.HasPrefix(line, `'`+includeKey+`'`) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// 以 src 递归覆盖 dst，两者均为表时按配置键合并，否则以 src 为准
+func mergeMap(dst map[string]interface{}, src map[string]interface{}) {
+	for k, v := range src {
+		sm, ok := v.(map[string]interface{})
+		dm, isMap := dst[k].(map[string]interface{})
+		if ok && isMap {
+			mergeMap(dm, sm)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// 获取配置文件全名，按 formats 的顺序查找配置目录下存在的配置文件，均不存在时为第一种格式的文件
 func (l localFile) getFullFileName(fileName string) (string, error) {
 	if fileName == "" {
 		return "", errors.New("未指定配置文件名称")
@@ -38,6 +139,11 @@ func (l localFile) getFullFileName(fileName string) (string, error) {
 	if fileName[:1] == "/" {
 		fileName = fileName[1:]
 	}
-	fileName = e.confDir + fileName + ".toml"
-	return fileName, nil
+	fileName = l.env.confDir + fileName
+	for _, f := range l.formats {
+		if info, err := os.Stat(fileName + f.ext); err == nil && !info.IsDir() {
+			return fileName + f.ext, nil
+		}
+	}
+	return fileName + l.formats[0].ext, nil
 }
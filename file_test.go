@@ -0,0 +1,49 @@
+package conf
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestInclude(t *testing.T) {
+	cl := newTestClient(t)
+	err := os.MkdirAll(cl.env.confDir+"../base/comm", 0775)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, cl, "../base/comm/app.toml", "title = \"base\"\n[db]\nhost = \"localhost\"\nport = 3306\n[log]\nlevel = \"info\"")
+	writeTestFile(t, cl, "comm/db.toml", "[db]\nhost = \"db.dev\"\nuser = \"dev\"")
+	writeTestFile(t, cl, "comm/app.toml", "\"@include\" = [\"../base/comm/app.toml\", \"comm.db\"]\ntitle = \"dev\"\n[log]\nlevel = \"debug\"")
+	l := newLocalFile(cl.env)
+	data, err := l.Load("comm.app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kvMap := make(map[string]Result)
+	err = setKvMap(data, make(confKeys, 0), kvMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &ConfigObject{data: kvMap, isExistence: true}
+	if c.Get("title").String() != "dev" || c.Get("log.level").String() != "debug" || c.Get("@include").Exists() {
+		t.Errorf("当前文件的配置应覆盖包含的文件...%v", c.All())
+	}
+	if c.Get("db.host").String() != "db.dev" || c.Get("db.port").Int() != 3306 || c.Get("db.user").String() != "dev" {
+		t.Errorf("包含的文件应按顺序合并...%v", c.All())
+	}
+	if len(l.files) != 3 {
+		t.Errorf("包含的文件应加入监听...%v", l.files)
+	}
+	writeTestFile(t, cl, "comm/db.toml", "\"@include\" = [\"comm.app\"]")
+	_, err = l.Load("comm.app")
+	if !errors.Is(err, ErrParse) || !strings.Contains(err.Error(), "循环包含") || !strings.Contains(err.Error(), "第1行") {
+		t.Errorf("循环包含应返回错误...%v", err)
+	}
+	writeTestFile(t, cl, "comm/app.toml", "title = \"dev\"\n\"@include\" = \"comm.none\"")
+	_, err = l.Load("comm.app")
+	if !errors.Is(err, ErrParse) || !strings.Contains(err.Error(), "app.toml第2行包含comm.none失败") {
+		t.Errorf("包含的文件不存在时应返回错误...%v", err)
+	}
+}
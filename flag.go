@@ -0,0 +1,183 @@
+package conf
+
+import (
+	"flag"
+	"strings"
+	"sync"
+)
+
+// FlagSource 命令行参数的适配接口，标准库 flag 通过 StdFlags 适配，pflag 等库实现此接口即可绑定
+type FlagSource interface {
+	// Flag 返回参数的当前值以及是否在命令行中显式设置，参数不存在时 changed 为false
+	Flag(name string) (value string, changed bool)
+}
+
+// 配置键绑定的命令行参数
+type flagBinding struct {
+	src  FlagSource
+	name string
+}
+
+// 命令行参数绑定列表，以配置标志以及完整配置键区分
+type flagBindings struct {
+	mutex sync.RWMutex
+	m     map[string]map[string]flagBinding
+}
+
+// StdFlags 以标准库 flag.FlagSet 作为命令行参数来源，fs 为nil时为 flag.CommandLine
+func StdFlags(fs *flag.FlagSet) FlagSource {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	return stdFlags{fs}
+}
+
+// 标准库命令行参数
+type stdFlags struct {
+	fs *flag.FlagSet
+}
+
+// Flag 实现 FlagSource 接口，Visit 只遍历显式设置的参数
+func (s stdFlags) Flag(name string) (string, bool) {
+	f := s.fs.Lookup(name)
+	if f == nil {
+		return "", false
+	}
+	changed := false
+	s.fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			changed = true
+		}
+	})
+	return f.Value.String(), changed
+}
+
+// 以配置值为默认值的命令行参数
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+// String 实现 flag.Value 接口
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+// Set 实现 flag.Value 接口
+func (v *flagValue) Set(s string) error {
+	v.value = s
+	return nil
+}
+
+// IsBoolFlag 配置值为布尔值时可以省略参数值，比如 -debug
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// Flag 在 fs(为nil时为 flag.CommandLine) 中定义命令行参数 name 并绑定到配置键，参数默认值为当前配置值，
+// 命令行中显式设置参数时 Get、All、Keys、Sub 等读取方法以及校验均以参数值为准，类型推断规则与环境变量覆盖一致，需在解析命令行参数之前调用
+func (c *ConfigObject) Flag(fs *flag.FlagSet, key string, name string, usage string) {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	r := c.Get(key)
+	v := &flagValue{isBool: r.dataType == Bool}
+	if r.Exists() {
+		v.value = r.String()
+	}
+	fs.Var(v, name, usage)
+	c.BindFlag(key, StdFlags(fs), name)
+}
+
+// BindFlag 将已定义的命令行参数绑定到配置键，用于 pflag 等其他命令行参数库，参数默认值需自行以配置值设置，
+// 配置对象不属于任何客户端时绑定不会生效
+func (c *ConfigObject) BindFlag(key string, src FlagSource, name string) {
+	if c.client == nil {
+		return
+	}
+	c.client.flags.add(c.fileName, joinKey(c.prefix, key), flagBinding{src, name})
+}
+
+// 添加绑定，同一配置键重复绑定时以最后一次为准
+func (b *flagBindings) add(fileName string, key string, binding flagBinding) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.m == nil {
+		b.m = make(map[string]map[string]flagBinding)
+	}
+	if b.m[fileName] == nil {
+		b.m[fileName] = make(map[string]flagBinding)
+	}
+	b.m[fileName][key] = binding
+}
+
+// 获取命令行中显式设置的参数值
+func (b *flagBindings) get(fileName string, key string) (Result, bool) {
+	b.mutex.RLock()
+	binding, ok := b.m[fileName][key]
+	b.mutex.RUnlock()
+	if !ok {
+		return Result{}, false
+	}
+	value, changed := binding.src.Flag(binding.name)
+	if !changed {
+		return Result{}, false
+	}
+	return genResult(InferValue(value)), true
+}
+
+// 获取指定前缀下命令行中显式设置的全部参数值，配置键去掉前缀
+func (b *flagBindings) values(fileName string, prefix string) map[string]Result {
+	b.mutex.RLock()
+	keys := make([]string, 0, len(b.m[fileName]))
+	for k := range b.m[fileName] {
+		if prefix == "" || strings.HasPrefix(k, prefix+".") {
+			keys = append(keys, k)
+		}
+	}
+	b.mutex.RUnlock()
+	v := make(map[string]Result)
+	for _, k := range keys {
+		if r, ok := b.get(fileName, k); ok {
+			if prefix != "" {
+				k = k[len(prefix)+1:]
+			}
+			v[k] = r
+		}
+	}
+	return v
+}
+
+// 获取合并命令行参数之后的全部配置，没有显式设置的参数时直接返回配置数据
+func (c *ConfigObject) merged() map[string]Result {
+	if c.client == nil {
+		return c.data
+	}
+	flags := c.client.flags.values(c.fileName, c.prefix)
+	if len(flags) == 0 {
+		return c.data
+	}
+	data := make(map[string]Result, len(c.data)+len(flags))
+	for k, v := range c.data {
+		data[k] = v
+	}
+	for k, v := range flags {
+		data[k] = v
+	}
+	return data
+}
+
+// 获取配置值，命令行中显式设置的参数优先
+func (c *ConfigObject) lookup(key string) (Result, bool) {
+	if c.client != nil {
+		r, ok := c.client.flags.get(c.fileName, joinKey(c.prefix, key))
+		if ok {
+			return r, true
+		}
+	}
+	r, ok := c.data[key]
+	return r, ok
+}
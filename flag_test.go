@@ -0,0 +1,68 @@
+package conf
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+// 模拟 pflag 等命令行参数库
+type mapFlags map[string]string
+
+func (m mapFlags) Flag(name string) (string, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+func TestFlag(t *testing.T) {
+	cl := newTestClient(t)
+	writeTestFile(t, cl, "comm/flag.toml", "debug = false\n[server]\naddr = \":8080\"\nworkers = 4")
+	c, err := cl.LoadConfig("comm.flag", SourceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	c.Flag(fs, "server.addr", "addr", "监听地址")
+	c.Sub("server").Flag(fs, "workers", "workers", "工作协程数")
+	c.Flag(fs, "debug", "debug", "调试模式")
+	if f := fs.Lookup("addr"); f == nil || f.DefValue != ":8080" {
+		t.Fatalf("命令行参数默认值应为配置值...%v", f)
+	}
+	err = fs.Parse([]string{"-workers", "8", "-debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ = cl.LoadConfig("comm.flag", SourceFile)
+	if c.Get("server.addr").String() != ":8080" || c.Get("server.workers").Int() != 8 || !c.Get("debug").Bool() {
+		t.Errorf("显式设置的命令行参数应覆盖配置值...%v %v", c.Get("server.workers").Value(), c.Get("debug").Value())
+	}
+	var s struct {
+		Workers int `conf:"workers"`
+	}
+	err = c.UnmarshalKey("server", &s)
+	if err != nil || s.Workers != 8 {
+		t.Errorf("Unmarshal 应使用命令行参数值...%v %v", s.Workers, err)
+	}
+	c.BindFlag("server.addr", mapFlags{"listen": ":9090"}, "listen")
+	if c.Get("server.addr").String() != ":9090" {
+		t.Errorf("适配接口绑定错误...%v", c.Get("server.addr").String())
+	}
+	if r := c.All()["server.workers"]; r.Int() != 8 || c.Sub("server").Get("addr").String() != ":9090" || c.StringMap("server")["workers"] != "8" {
+		t.Errorf("All、Sub、StringMap 应使用命令行参数值...%v", c.All())
+	}
+	c.BindFlag("server.tls", mapFlags{"tls": "true"}, "tls")
+	if keys := c.Keys("server"); len(keys) != 3 || keys[1] != "server.tls" {
+		t.Errorf("Keys 应包含显式设置的命令行参数...%v", keys)
+	}
+	cl.RegisterValidator("comm.flag", func(co *ConfigObject) error {
+		if r := co.All()["server.workers"]; r.Int() != 8 {
+			return errors.New("校验未使用命令行参数值")
+		}
+		return nil
+	})
+	if _, err = cl.genConfigObject("comm.flag", SourceFile, map[string]interface{}{"server": map[string]interface{}{"workers": int64(2)}}); err != nil {
+		t.Errorf("校验应使用命令行参数值...%v", err)
+	}
+}
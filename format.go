@@ -0,0 +1,136 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// 配置文件格式
+type fileFormat struct {
+	// 文件后缀
+	ext string
+	// 解析文件内容
+	decode func(content []byte) (map[string]interface{}, error)
+}
+
+// 支持的配置文件格式，同一配置标志存在多个格式的配置文件时按此顺序优先
+var formats = []fileFormat{
+	{".toml", decodeTOML},
+	{".yaml", decodeYAML},
+	{".yml", decodeYAML},
+	{".json", decodeJSON},
+	{".properties", decodeProperties},
+	{".ini", decodeINI},
+}
+
+// 以文件后缀获取配置文件格式，未知后缀按第一种格式解析
+func formatOf(list []fileFormat, name string) fileFormat {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, f := range list {
+		if f.ext == ext {
+			return f
+		}
+	}
+	return list[0]
+}
+
+// 判断是否为支持的配置文件后缀
+func isFormatExt(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, f := range formats {
+		if f.ext == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析toml
+func decodeTOML(content []byte) (map[string]interface{}, error) {
+	var data = make(map[string]interface{})
+	_, err := toml.Decode(string(content), &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// 解析yaml，配置值类型与toml保持一致
+func decodeYAML(content []byte) (map[string]interface{}, error) {
+	var data = make(map[string]interface{})
+	err := yaml.Unmarshal(content, &data)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeMap(data), nil
+}
+
+// 解析json，配置值类型与toml保持一致
+func decodeJSON(content []byte) (map[string]interface{}, error) {
+	var data = make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	err := decoder.Decode(&data)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeMap(data), nil
+}
+
+// 规范化表中的配置值
+func normalizeMap(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		m[k] = normalize(v)
+	}
+	return m
+}
+
+// 规范化配置值，与toml解析结果保持一致:整数为int64，表为map[string]interface{}，
+// 元素均为表的数组为[]map[string]interface{}，RFC3339格式的字符串为时间
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return normalizeMap(x)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			m[fmt.Sprintf("%v", k)] = normalize(e)
+		}
+		return m
+	case []interface{}:
+		tables := make([]map[string]interface{}, 0, len(x))
+		for i, e := range x {
+			x[i] = normalize(e)
+			if m, ok := x[i].(map[string]interface{}); ok {
+				tables = append(tables, m)
+			}
+		}
+		if len(x) > 0 && len(tables) == len(x) {
+			return tables
+		}
+		return x
+	case int:
+		return int64(x)
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return x.String()
+		}
+		return f
+	case string:
+		if t, err := time.Parse(time.RFC3339, x); err == nil {
+			return t
+		}
+	}
+	return v
+}
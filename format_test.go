@@ -0,0 +1,145 @@
+package conf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormats(t *testing.T) {
+	cl := newTestClient(t)
+	writeTestFile(t, cl, "comm/toml.toml", `
+dob = 2018-05-27T07:32:00Z
+[base]
+int = 1
+float = 1.5
+hosts = ["a", "b"]
+[[servers]]
+ip = "10.0.0.1"
+`)
+	writeTestFile(t, cl, "comm/yaml.yaml", `
+dob: 2018-05-27T07:32:00Z
+base:
+  int: 1
+  float: 1.5
+  hosts: [a, b]
+servers:
+  - ip: 10.0.0.1
+`)
+	writeTestFile(t, cl, "comm/yml.yml", "base:\n  int: 1\n")
+	writeTestFile(t, cl, "comm/json.json", `{
+	"dob": "2018-05-27T07:32:00Z",
+	"base": {"int": 1, "float": 1.5, "hosts": ["a", "b"]},
+	"servers": [{"ip": "10.0.0.1"}]
+}`)
+	dob, _ := time.Parse(time.RFC3339, "2018-05-27T07:32:00Z")
+	for _, fileName := range []string{"comm.toml", "comm.yaml", "comm.json"} {
+		c, err := cl.LoadConfig(fileName, SourceFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Get("base.int").Value() != int64(1) || c.Get("base.float").Value() != 1.5 || !c.Get("dob").Time().Equal(dob) {
+			t.Errorf("%s 配置值类型不一致...%v", fileName, c.All())
+		}
+		if hosts := c.Get("base.hosts").StringSlice(); len(hosts) != 2 || hosts[1] != "b" {
+			t.Errorf("%s 数组读取错误...%v", fileName, hosts)
+		}
+		if _, ok := c.Get("servers").Value().([]map[string]interface{}); !ok || c.Get("servers").SliceMap()[0]["ip"] != "10.0.0.1" {
+			t.Errorf("%s 表数组类型不一致...%T", fileName, c.Get("servers").Value())
+		}
+	}
+	c, err := cl.LoadConfig("comm.yml", SourceFile)
+	if err != nil || c.Get("base.int").Int() != 1 {
+		t.Errorf("yml 读取错误...%v", err)
+	}
+	// 同一配置标志存在多个格式时toml优先
+	writeTestFile(t, cl, "comm/yml.toml", "[base]\nint = 2")
+	cl.DisableCache()
+	c, err = cl.LoadConfig("comm.yml", SourceFile)
+	if err != nil || c.Get("base.int").Int() != 2 {
+		t.Errorf("toml 应优先...%v", err)
+	}
+}
+
+func TestProperties(t *testing.T) {
+	data, err := decodeProperties([]byte(`# 注释
+! 注释
+db.master.addr = 10.0.0.1:3306
+db.master = master
+db.port:3306
+db.rate 0.5
+debug=true
+key\ with\ space = a\tb
+unicode = \u4e2d\u6587
+multi = first, \
+        second, \
+        third
+path = C:\\web
+empty
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]interface{}{
+		"db.master.addr": "10.0.0.1:3306",
+		"db.master":      "master",
+		"db.port":        int64(3306),
+		"db.rate":        0.5,
+		"debug":          true,
+		"key with space": "a\tb",
+		"unicode":        "中文",
+		"multi":          "first, second, third",
+		"path":           `C:\web`,
+		"empty":          "",
+	}
+	if len(data) != len(cases) {
+		t.Errorf("properties 解析错误...%v", data)
+	}
+	for k, v := range cases {
+		if data[k] != v {
+			t.Errorf("properties 配置[%s]解析错误...%v(%T)", k, data[k], data[k])
+		}
+	}
+}
+
+func TestINI(t *testing.T) {
+	cl := newTestClient(t)
+	writeTestFile(t, cl, "comm/legacy.ini", `; 注释
+title = legacy
+[db.master]
+addr = 10.0.0.1:3306
+port: 3306
+# 注释
+desc = first line
+  second line
+[log]
+level = "debug"
+path = /var/log/\
+web.log
+`)
+	c, err := cl.LoadConfig("comm.legacy", SourceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Get("title").String() != "legacy" || c.Get("db.master.addr").String() != "10.0.0.1:3306" || c.Get("db.master.port").Value() != int64(3306) {
+		t.Errorf("ini 解析错误...%v", c.All())
+	}
+	if c.Get("db.master.desc").String() != "first line\nsecond line" || c.Get("log.level").String() != "debug" || c.Get("log.path").String() != "/var/log/web.log" {
+		t.Errorf("ini 解析错误...%v", c.All())
+	}
+	data, err := decodeINI([]byte("[db]\n  host = x\n  port = 1\n\tuser: root"))
+	if err != nil || data["db.host"] != "x" || data["db.port"] != int64(1) || data["db.user"] != "root" {
+		t.Errorf("缩进的配置键解析错误...%v %v", data, err)
+	}
+	data, err = decodeINI([]byte("[web]\nurls = http://a:8080/x\n  http://b:8080/y?q=1\n  port = 80"))
+	if err != nil || data["web.urls"] != "http://a:8080/x\nhttp://b:8080/y?q=1" || data["web.port"] != int64(80) {
+		t.Errorf("含分隔符的配置值延续行解析错误...%v %v", data, err)
+	}
+	_, err = decodeINI([]byte("[db\naddr = x"))
+	if err == nil {
+		t.Error("节名称错误时应返回错误...")
+	}
+	_, err = decodeINI([]byte("[db]\naddr"))
+	if err == nil || err.Error() != "第2行:缺少\"=\":addr" {
+		t.Errorf("缺少\"=\"时应返回错误...%v", err)
+	}
+}
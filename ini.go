@@ -0,0 +1,80 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 解析.ini配置文件，节名称作为配置键前缀(比如 [db.master] 下的 addr 为 db.master.addr)，
+// 以";"或者"#"开头的行为注释，缩进且不以配置键开头的行以及以反斜杠结尾的行为上一行配置值的延续，配置值按toml配置值推断类型
+func decodeINI(content []byte) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	section := ""
+	key := ""
+	var value []string
+	// 保存上一个配置键，多行配置值以换行拼接
+	flush := func() {
+		if key != "" {
+			data[joinKey(section, key)] = InferValue(strings.Join(value, "\n"))
+		}
+		key, value = "", nil
+	}
+	lines := strings.Split(strings.Replace(string(content), "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			flush()
+			continue
+		}
+		if line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		// 缩进的配置键是常见写法，分隔符之前不是合法配置键的缩进行才是配置值的延续(比如 http://host:8080/x)
+		if key != "" && unicode.IsSpace(rune(raw[0])) && !iniKey(line) {
+			value = append(value, line)
+			continue
+		}
+		flush()
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("第%d行:节名称缺少\"]\"", i+1)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		n := strings.IndexAny(line, "=:")
+		if n <= 0 {
+			return nil, fmt.Errorf("第%d行:缺少\"=\":%s", i+1, line)
+		}
+		key = strings.TrimSpace(line[:n])
+		value = []string{strings.TrimSpace(line[n+1:])}
+		// 以反斜杠结尾的行与下一行拼接
+		for continued(value[0]) && i+1 < len(lines) {
+			i++
+			value[0] = value[0][:len(value[0])-1] + strings.TrimSpace(lines[i])
+		}
+	}
+	flush()
+	return data, nil
+}
+
+// 判断行是否以配置键开头，配置键由字母、数字以及"_"、"-"、"."组成，与分隔符之间可以有空白，
+// 紧跟"//"的":"是URL的协议分隔符而不是配置键分隔符
+func iniKey(line string) bool {
+	n := strings.IndexAny(line, "=:")
+	if n <= 0 || strings.HasPrefix(line[n:], "://") {
+		return false
+	}
+	key := strings.TrimSpace(line[:n])
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 配置值引用解析，支持 ${other.key}、${env:HOME}、${file:/run/secrets/x}，$${ 表示 ${ 本身
+type interpolator struct {
+	kvMap map[string]Result
+	// ${file:x} 相对路径的基准目录
+	confDir  string
+	resolved map[string]bool
+	visiting map[string]bool
+}
+
+// 解析字符串配置值中的引用，每次加载配置时重新解析，被引用的配置键变更之后引用它的配置值随之更新
+func (cl *Client) interpolate(kvMap map[string]Result) error {
+	if !cl.interpolation {
+		return nil
+	}
+	ip := &interpolator{
+		kvMap:    kvMap,
+		confDir:  cl.env.confDir,
+		resolved: make(map[string]bool),
+		visiting: make(map[string]bool),
+	}
+	keys := make([]string, 0, len(kvMap))
+	for k := range kvMap {
+		keys = append(keys, k)
+	}
+	// 按配置键顺序解析，保证错误信息稳定
+	sort.Strings(keys)
+	for _, k := range keys {
+		err := ip.resolve(k, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 解析一个配置键，path 为引用链，用于检测循环引用
+func (ip *interpolator) resolve(key string, path []string) error {
+	if ip.resolved[key] {
+		return nil
+	}
+	path = append(path, key)
+	if ip.visiting[key] {
+		return errors.New("配置值循环引用:" + strings.Join(path, " -> "))
+	}
+	s, ok := ip.kvMap[key].value.(string)
+	if !ok || !strings.Contains(s, "${") {
+		ip.resolved[key] = true
+		return nil
+	}
+	ip.visiting[key] = true
+	v, err := ip.expand(s, path)
+	delete(ip.visiting, key)
+	if err != nil {
+		return err
+	}
+	ip.kvMap[key] = genResult(v)
+	ip.resolved[key] = true
+	return nil
+}
+
+// 替换配置值中的引用，整个配置值为单个引用时保留被引用配置值的类型
+func (ip *interpolator) expand(s string, path []string) (interface{}, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			b.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		end := strings.Index(s[i+2:], "}")
+		if end == -1 {
+			return nil, fmt.Errorf("配置[%s]:引用缺少\"}\":%s", path[len(path)-1], s)
+		}
+		v, err := ip.value(s[i+2:i+2+end], path)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && 3+end == len(s) {
+			return v, nil
+		}
+		b.WriteString(fmt.Sprintf("%v", v))
+		i += 3 + end
+	}
+	return b.String(), nil
+}
+
+// 获取引用的值
+func (ip *interpolator) value(ref string, path []string) (interface{}, error) {
+	key := path[len(path)-1]
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		v, ok := os.LookupEnv(ref[len("env:"):])
+		if !ok {
+			return nil, fmt.Errorf("配置[%s]:引用的环境变量%s不存在", key, ref[len("env:"):])
+		}
+		return v, nil
+	case strings.HasPrefix(ref, "file:"):
+		name := ref[len("file:"):]
+		if !filepath.IsAbs(name) {
+			name = ip.confDir + name
+		}
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("配置[%s]:引用的文件读取失败:%v", key, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	err := ip.resolve(ref, path)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := ip.kvMap[ref]
+	if !ok {
+		return nil, fmt.Errorf("配置[%s]:引用的配置键%s不存在", key, ref)
+	}
+	return r.value, nil
+}
@@ -0,0 +1,70 @@
+package conf
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestInterpolate(t *testing.T) {
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t, WithCallback(cb), WithInterpolation())
+	writeTestFile(t, cl, "secret", "p@ss\n")
+	os.Setenv("WEB_GO_CONFIG_TEST_HOME", "/home/web")
+	defer os.Unsetenv("WEB_GO_CONFIG_TEST_HOME")
+	data := map[string]interface{}{
+		"host": "db.local",
+		"port": int64(3306),
+		"db": map[string]interface{}{
+			"addr":     "${host}:${port}",
+			"port":     "${port}",
+			"dsn":      "root:${db.password}@tcp(${db.addr})/web",
+			"password": "${file:secret}",
+		},
+		"log":     "${env:WEB_GO_CONFIG_TEST_HOME}/log",
+		"escaped": "$${host}",
+	}
+	c, err := cl.genConfigObject("interpolate-test", SourceFile, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Get("db.dsn").String() != "root:p@ss@tcp(db.local:3306)/web" || c.Get("log").String() != "/home/web/log" {
+		t.Errorf("配置值引用解析错误...%v", c.All())
+	}
+	if c.Get("db.port").Value() != int64(3306) || c.Get("escaped").String() != "${host}" {
+		t.Errorf("配置值引用解析错误...%v", c.All())
+	}
+	<-cb
+	// 被引用的配置键变更之后重新解析
+	data["host"] = "db.remote"
+	_, err = cl.genConfigObject("interpolate-test", SourceFile, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case co := <-cb:
+		if co.Get("db.addr").String() != "db.remote:3306" {
+			t.Errorf("被引用的配置键变更之后应重新解析...%v", co.Get("db.addr").String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("被引用的配置键变更之后没有回调...")
+	}
+	for _, v := range []map[string]interface{}{
+		{"a": "${b}", "b": "${c}", "c": "${a}"},
+		{"a": "${none}"},
+		{"a": "${env:WEB_GO_CONFIG_TEST_NONE}"},
+		{"a": "${a"},
+	} {
+		_, err = cl.genConfigObject("interpolate-error", SourceFile, v)
+		if !errors.Is(err, ErrParse) {
+			t.Errorf("错误的引用应返回解析错误...%v", err)
+		}
+	}
+	// 默认不解析引用
+	cl = newTestClient(t)
+	c, err = cl.genConfigObject("interpolate-test", SourceFile, map[string]interface{}{"a": "${b}"})
+	if err != nil || c.Get("a").String() != "${b}" {
+		t.Errorf("不解析引用时应保留原配置值...%v", err)
+	}
+}
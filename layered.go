@@ -0,0 +1,174 @@
+package conf
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Layer 配置层
+type Layer struct {
+	// Name 配置层名称，为空时以配置标志命名，用于 Origin 查询配置键来源
+	Name string
+	// FileName 配置标志
+	FileName string
+	// Source 配置来源
+	Source Source
+	// Optional 是否可选，可选的配置层加载失败时忽略此层
+	Optional bool
+}
+
+// LayeredConfig 多层合并的配置，后面的配置层覆盖前面的配置层，任意配置层有变更时重新合并
+type LayeredConfig struct {
+	cl     *Client
+	name   string
+	layers []Layer
+	mutex  sync.RWMutex
+	// 各配置层当前的配置对象，与 layers 一一对应
+	objects []*ConfigObject
+	// 配置键来源的配置层名称
+	origins map[string]string
+	// 是否已完成首次合并
+	ready bool
+}
+
+// NewLayeredConfig 按顺序加载各配置层并合并为名为 name 的配置，后面的配置层优先，比如:
+/**
+lc, err := cl.NewLayeredConfig("app",
+	conf.Layer{Name: "default", FileName: "comm.app", Source: conf.SourceFile},
+	conf.Layer{Name: "xdiamond", FileName: "web.app", Source: conf.SourceXdaTCP},
+)
+**/
+// 合并之后的配置以 name 为配置标志，可以通过 LoadConfig(name, SourceLayered) 获取，变更时与其他配置一样回调
+func (cl *Client) NewLayeredConfig(name string, layers ...Layer) (*LayeredConfig, error) {
+	if name == "" {
+		return nil, newError(ErrNotFound, name, SourceLayered, errors.New("未指定配置标志"))
+	}
+	lc := &LayeredConfig{
+		cl:      cl,
+		name:    name,
+		layers:  make([]Layer, len(layers)),
+		objects: make([]*ConfigObject, len(layers)),
+	}
+	seen := make(map[string]bool)
+	for i, layer := range layers {
+		if layer.FileName == name || seen[layer.FileName] {
+			// 参数错误，不属于任何配置错误类别
+			return nil, errors.New("配置[" + name + "]的配置层" + layer.FileName + "重复")
+		}
+		seen[layer.FileName] = true
+		if layer.Name == "" {
+			layer.Name = layer.FileName
+		}
+		lc.layers[i] = layer
+	}
+	// 先添加变更回调，避免遗漏加载过程中的配置变更
+	cl.AddChangeHandler(lc)
+	for i, layer := range lc.layers {
+		co, err := cl.LoadConfig(layer.FileName, layer.Source)
+		if err != nil {
+			if layer.Optional {
+				cl.log.Warning("配置层", layer.Name, "加载失败,已忽略:", err)
+				continue
+			}
+			cl.RemoveChangeHandler(lc)
+			return nil, err
+		}
+		lc.mutex.Lock()
+		// 加载过程中有变更时以变更之后的配置为准
+		if lc.objects[i] == nil {
+			lc.objects[i] = co
+		}
+		lc.mutex.Unlock()
+	}
+	lc.mutex.Lock()
+	lc.ready = true
+	lc.mutex.Unlock()
+	err := lc.merge()
+	if err != nil {
+		cl.RemoveChangeHandler(lc)
+		return nil, err
+	}
+	return lc, nil
+}
+
+// Config 获取合并之后的当前配置
+func (lc *LayeredConfig) Config() *ConfigObject {
+	lc.cl.mutex.RLock()
+	defer lc.cl.mutex.RUnlock()
+	co, ok := lc.cl.data[lc.name]
+	if !ok {
+		return new(ConfigObject)
+	}
+	return &co
+}
+
+// Origin 获取配置键当前生效值所在的配置层名称，配置键不存在时返回空字符串
+func (lc *LayeredConfig) Origin(key string) string {
+	lc.mutex.RLock()
+	defer lc.mutex.RUnlock()
+	return lc.origins[key]
+}
+
+// Close 停止跟随配置层变更
+func (lc *LayeredConfig) Close() {
+	lc.cl.RemoveChangeHandler(lc)
+}
+
+// ChangeHandel 配置层有变更时重新合并
+func (lc *LayeredConfig) ChangeHandel(fileName string, cs *ChangeSet) {
+	lc.mutex.Lock()
+	changed := false
+	for i, layer := range lc.layers {
+		if layer.FileName == fileName {
+			lc.objects[i] = cs.New
+			changed = true
+		}
+	}
+	ready := lc.ready
+	lc.mutex.Unlock()
+	if !changed || !ready {
+		return
+	}
+	err := lc.merge()
+	if err != nil {
+		lc.cl.reject(lc.name, err)
+	}
+}
+
+// 按配置层顺序合并，校验通过之后以合并之后的配置保存
+func (lc *LayeredConfig) merge() error {
+	// 持有锁直到保存，保证合并结果按顺序生效
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	data := make(map[string]Result)
+	origins := make(map[string]string)
+	overridden := make(map[string]bool)
+	for i, co := range lc.objects {
+		if co == nil {
+			continue
+		}
+		for k, v := range co.data {
+			data[k] = v
+			origins[k] = lc.layers[i].Name
+			delete(overridden, k)
+		}
+		for _, k := range co.overridden {
+			overridden[k] = true
+		}
+	}
+	keys := make([]string, 0, len(overridden))
+	for k := range overridden {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	co := ConfigObject{data, true, SourceLayered, lc.name, lc.cl, "", keys}
+	lc.cl.setDefaults(lc.name, &co)
+	err := lc.cl.validate(lc.name, SourceLayered, &co)
+	if err != nil {
+		return err
+	}
+	lc.origins = origins
+	lc.cl.save(lc.name, co)
+	return nil
+}
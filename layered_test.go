@@ -0,0 +1,65 @@
+package conf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLayeredConfig(t *testing.T) {
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t)
+	writeTestFile(t, cl, "comm/base.toml", "[db]\nhost = \"localhost\"\nport = 3306")
+	writeTestFile(t, cl, "comm/override.toml", "[db]\nhost = \"10.0.0.1\"")
+	_, err := cl.NewLayeredConfig("app", Layer{FileName: "comm.base", Source: SourceFile}, Layer{Name: "none", FileName: "comm.none", Source: SourceFile})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("必需的配置层加载失败时应返回错误...%v", err)
+	}
+	_, err = cl.NewLayeredConfig("app", Layer{FileName: "comm.base", Source: SourceFile}, Layer{FileName: "comm.base", Source: SourceFile})
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("重复的配置层应返回参数错误...%v", err)
+	}
+	lc, err := cl.NewLayeredConfig("app",
+		Layer{Name: "default", FileName: "comm.base", Source: SourceFile},
+		Layer{Name: "none", FileName: "comm.none", Source: SourceFile, Optional: true},
+		Layer{Name: "override", FileName: "comm.override", Source: SourceFile},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lc.Close()
+	c := lc.Config()
+	if c.Get("db.host").String() != "10.0.0.1" || c.Get("db.port").Int() != 3306 {
+		t.Errorf("配置层合并错误...%v", c.All())
+	}
+	if lc.Origin("db.host") != "override" || lc.Origin("db.port") != "default" || lc.Origin("db.user") != "" {
+		t.Errorf("配置键来源错误...%v %v", lc.Origin("db.host"), lc.Origin("db.port"))
+	}
+	if c, err := cl.LoadConfig("app", SourceLayered); err != nil || c.Get("db.host").String() != "10.0.0.1" {
+		t.Errorf("合并之后的配置读取错误...%v", err)
+	}
+	cl.AddCallback(cb)
+	// 上层配置删除配置键之后以下层配置为准
+	_, err = cl.genConfigObject("comm.override", SourceFile, map[string]interface{}{"db": map[string]interface{}{"port": int64(3307)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		select {
+		case co := <-cb:
+			// 跳过首次合并的回调
+			if co.fileName != "app" || co.Get("db.port").Int() == 3306 {
+				continue
+			}
+			if co.Get("db.host").String() != "localhost" || co.Get("db.port").Int() != 3307 {
+				t.Errorf("配置层变更之后重新合并错误...%v", co.All())
+			}
+			if lc.Origin("db.host") != "default" || lc.Origin("db.port") != "override" {
+				t.Errorf("配置键来源错误...%v %v", lc.Origin("db.host"), lc.Origin("db.port"))
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatal("配置层变更之后没有重新合并...")
+		}
+	}
+}
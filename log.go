@@ -8,7 +8,7 @@ import (
 )
 
 //Log 供外部使用的全局日志变量
-var Log *loger
+var Log = newLoger(os.Stdout)
 
 type loger struct {
 	//Debug 调试日志
@@ -44,14 +44,16 @@ const (
 	Non
 )
 
-func logInit() {
-	Log = new(loger)
-	Log.debug = log.New(os.Stdout, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile)
-	Log.info = log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
-	Log.warning = log.New(os.Stdout, "WARNING: ", log.Ldate|log.Ltime|log.Lshortfile)
-	Log.err = log.New(os.Stdout, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
-	Log.fatal = log.New(os.Stdout, "FATAL: ", log.Ldate|log.Ltime|log.Lshortfile)
-	Log.logLevel = 0
+// 实例化一个日志对象
+func newLoger(w io.Writer) *loger {
+	l := new(loger)
+	l.debug = log.New(w, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile)
+	l.info = log.New(w, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
+	l.warning = log.New(w, "WARNING: ", log.Ldate|log.Ltime|log.Lshortfile)
+	l.err = log.New(w, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
+	l.fatal = log.New(w, "FATAL: ", log.Ldate|log.Ltime|log.Lshortfile)
+	l.logLevel = 0
+	return l
 }
 
 // SetLogOutput 设置日志输出
@@ -59,14 +61,19 @@ func SetLogOutput(w io.Writer, lv LogLevel) error {
 	if Log == nil {
 		return errors.New("日志模块未初始化")
 	}
-	Log.debug.SetOutput(w)
-	Log.info.SetOutput(w)
-	Log.warning.SetOutput(w)
-	Log.err.SetOutput(w)
-	Log.fatal.SetOutput(w)
+	Log.setOutput(w)
 	return nil
 }
 
+// 设置日志输出
+func (l *loger) setOutput(w io.Writer) {
+	l.debug.SetOutput(w)
+	l.info.SetOutput(w)
+	l.warning.SetOutput(w)
+	l.err.SetOutput(w)
+	l.fatal.SetOutput(w)
+}
+
 //Debug 打印调试日志
 func (l *loger) Debug(args ...interface{}) {
 	if l.logLevel > Debug {
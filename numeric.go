@@ -0,0 +1,115 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	errOverflow   = errors.New("数值溢出")
+	errFraction   = errors.New("浮点数不是整数")
+	errNegative   = errors.New("负数无法转换为无符号数")
+	errNotNumeric = errors.New("不是数值类型")
+)
+
+// 将任意数值类型转换为int64，浮点数必须为整数值且不超出int64范围
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int8:
+		return int64(n), nil
+	case int16:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint, uint8, uint16, uint32, uint64:
+		u, _ := toUint64(n)
+		if u > math.MaxInt64 {
+			return 0, errOverflow
+		}
+		return int64(u), nil
+	case float32:
+		return floatToInt64(float64(n))
+	case float64:
+		return floatToInt64(n)
+	}
+	return 0, fmt.Errorf("%T %w", v, errNotNumeric)
+}
+
+// 将任意数值类型转换为uint64，负数、非整数以及超出uint64范围的浮点数返回错误
+func toUint64(v interface{}) (uint64, error) {
+	switch n := v.(type) {
+	case uint:
+		return uint64(n), nil
+	case uint8:
+		return uint64(n), nil
+	case uint16:
+		return uint64(n), nil
+	case uint32:
+		return uint64(n), nil
+	case uint64:
+		return n, nil
+	case float32, float64:
+		f, _ := toFloat64(n)
+		if f != math.Trunc(f) {
+			return 0, errFraction
+		}
+		if f < 0 {
+			return 0, errNegative
+		}
+		// float64(math.MaxUint64) 实际为 2^64，等于时同样溢出
+		if f >= math.MaxUint64 {
+			return 0, errOverflow
+		}
+		return uint64(f), nil
+	}
+	i, err := toInt64(v)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, errNegative
+	}
+	return uint64(i), nil
+}
+
+// 将任意数值类型转换为float64
+func toFloat64(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float32:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case uint:
+		return float64(n), nil
+	case uint8:
+		return float64(n), nil
+	case uint16:
+		return float64(n), nil
+	case uint32:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	}
+	i, err := toInt64(v)
+	if err != nil {
+		return 0, err
+	}
+	return float64(i), nil
+}
+
+// 浮点数转int64
+func floatToInt64(f float64) (int64, error) {
+	if f != math.Trunc(f) {
+		return 0, errFraction
+	}
+	// float64(math.MaxInt64) 实际为 2^63，等于时同样溢出
+	if f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, errOverflow
+	}
+	return int64(f), nil
+}
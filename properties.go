@@ -0,0 +1,98 @@
+package conf
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 解析java风格的.properties配置文件，配置键保持原样(比如 db.master.addr)，配置值按toml配置值推断类型
+func decodeProperties(content []byte) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	lines := strings.Split(strings.Replace(string(content), "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimLeftFunc(lines[i], unicode.IsSpace)
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// 以奇数个反斜杠结尾的行与下一行拼接，下一行的前导空白忽略
+		for continued(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeftFunc(lines[i], unicode.IsSpace)
+		}
+		if continued(line) {
+			line = line[:len(line)-1]
+		}
+		key, value := splitProperty(line)
+		data[unescapeProperty(key)] = InferValue(unescapeProperty(value))
+	}
+	return data, nil
+}
+
+// 判断是否以奇数个反斜杠结尾
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// 以第一个未转义的"="、":"或者空白分隔配置键与配置值
+func splitProperty(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':', ' ', '\t', '\f':
+			key := line[:i]
+			value := strings.TrimLeft(line[i:], " \t\f")
+			if line[i] == ' ' || line[i] == '\t' || line[i] == '\f' {
+				if value != "" && (value[0] == '=' || value[0] == ':') {
+					value = value[1:]
+				}
+			} else {
+				value = value[1:]
+			}
+			return key, strings.TrimLeft(value, " \t\f")
+		}
+	}
+	return line, ""
+}
+
+// 处理转义字符，比如 \t \n \uXXXX，其他字符前的反斜杠忽略
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 <= len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
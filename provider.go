@@ -0,0 +1,95 @@
+package conf
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// Provider 配置源统一接口，以配置标志加载配置数据，配置数据可以是嵌套的表(map[string]interface{})，
+// 配置值类型建议与toml解析结果一致，比如整数为int64
+type Provider interface {
+	Load(fileName string) (map[string]interface{}, error)
+}
+
+// Watcher 支持推送配置变更的配置源可选实现此接口，首次加载成功之后调用，ctx 在客户端关闭时结束，
+// 配置变更时以新的配置数据调用 update，重新加载失败时以错误调用 update
+type Watcher interface {
+	Watch(ctx context.Context, fileName string, update func(data map[string]interface{}, err error)) error
+}
+
+// EnvProvider 按配置环境区分配置数据的配置源可选实现此接口，客户端加载配置时以自身的配置环境(WithEnv)调用 ForEnv，
+// 以返回的 Provider 加载以及监听配置，同一配置源可以被不同配置环境的客户端共用
+type EnvProvider interface {
+	ForEnv(env string) Provider
+}
+
+// 第三方配置源编号起始值
+const sourceCustom Source = 100
+
+// 已注册的配置源
+var registry = struct {
+	mutex     sync.RWMutex
+	providers map[Source]Provider
+	names     map[Source]string
+}{
+	providers: make(map[Source]Provider),
+	names:     make(map[Source]string),
+}
+
+// 内置配置源名称
+var sourceNames = map[Source]string{
+	SourceFile:    "file",
+	SourceXdaHTTP: "xdiamond-http",
+	SourceXdaTCP:  "xdiamond-tcp",
+	SourceBackups: "backups",
+	SourceLayered: "layered",
+	SourceDotenv:  "dotenv",
+}
+
+// RegisterSource 注册一个第三方配置源，比如 etcd、Consul、Vault，返回的 Source 用于 NewConfig、LoadConfig，
+// 与配置中心一样缓存、备份(配置源不可用时从本地备份恢复)以及回调;同名配置源重复注册时替换原配置源并返回相同的 Source
+func RegisterSource(name string, p Provider) Source {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	for s, n := range registry.names {
+		if n == name {
+			registry.providers[s] = p
+			return s
+		}
+	}
+	s := sourceCustom + Source(len(registry.names))
+	registry.providers[s] = p
+	registry.names[s] = name
+	return s
+}
+
+// 获取已注册的配置源
+func getProvider(s Source) (Provider, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	p, ok := registry.providers[s]
+	return p, ok
+}
+
+// String 返回配置源名称
+func (s Source) String() string {
+	if name, ok := sourceNames[s]; ok {
+		return name
+	}
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	if name, ok := registry.names[s]; ok {
+		return name
+	}
+	return "source(" + strconv.Itoa(int(s)) + ")"
+}
+
+// 是否为远程配置源，远程配置源的配置数据会备份到本地，不可用时从本地备份恢复
+func (s Source) remote() bool {
+	switch s {
+	case SourceFile, SourceBackups, SourceLayered, SourceDotenv:
+		return false
+	}
+	return true
+}
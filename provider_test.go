@@ -0,0 +1,105 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// 内存配置源，模拟第三方配置中心
+type memProvider struct {
+	data    map[string]interface{}
+	err     error
+	updates chan map[string]interface{}
+}
+
+func (m *memProvider) Load(fileName string) (map[string]interface{}, error) {
+	return m.data, m.err
+}
+
+func (m *memProvider) Watch(ctx context.Context, fileName string, update func(map[string]interface{}, error)) error {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case data := <-m.updates:
+				update(data, nil)
+			}
+		}
+	}()
+	return nil
+}
+
+func TestRegisterSource(t *testing.T) {
+	p := &memProvider{data: map[string]interface{}{"port": int64(8080)}, updates: make(chan map[string]interface{})}
+	source := RegisterSource("mem", p)
+	if RegisterSource("mem", p) != source || source.String() != "mem" || SourceXdaTCP.String() != "xdiamond-tcp" {
+		t.Fatalf("配置源注册错误...%v", source)
+	}
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t, WithCallback(cb))
+	c, err := cl.LoadConfig("mem.app", source)
+	if err != nil || c.Get("port").Int() != 8080 {
+		t.Fatalf("第三方配置源读取错误...%v", err)
+	}
+	<-cb
+	p.updates <- map[string]interface{}{"port": int64(9090)}
+	select {
+	case co := <-cb:
+		if co.Get("port").Int() != 9090 {
+			t.Errorf("第三方配置源推送变更错误...%v", co.All())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("第三方配置源推送变更之后没有回调...")
+	}
+	// 配置源不可用时从本地备份恢复
+	p.err = errors.New("connection refused")
+	cl2, err := New(WithConfigPath(cl.confPath), WithEnv("dev"), WithoutLogFile(), withLoger(cl.log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl2.Close()
+	c, err = cl2.LoadConfig("mem.app", source)
+	if err != nil || c.Get("port").Int() != 9090 {
+		t.Errorf("第三方配置源不可用时应从本地备份恢复...%v", err)
+	}
+	_, err = cl2.LoadConfig("mem.none", source)
+	if !errors.Is(err, ErrBackupRecovery) {
+		t.Errorf("没有备份时应返回错误...%v", err)
+	}
+	_, err = cl.LoadConfig("mem.app", Source(99))
+	if !errors.Is(err, ErrUnsupportedSource) {
+		t.Errorf("未注册的配置源应返回错误...%v", err)
+	}
+}
+
+// 按配置环境区分配置数据的内存配置源
+type envProvider map[string]map[string]interface{}
+
+func (e envProvider) ForEnv(env string) Provider {
+	return &memProvider{data: e[env]}
+}
+
+func (e envProvider) Load(fileName string) (map[string]interface{}, error) {
+	return nil, errors.New("未指定配置环境")
+}
+
+func TestEnvProvider(t *testing.T) {
+	source := RegisterSource("mem-env", envProvider{"dev": {"debug": true}, "test": {"debug": false}})
+	c, err := newTestClient(t).LoadConfig("comm.app", source)
+	if err != nil || !c.Get("debug").Bool() {
+		t.Errorf("应以客户端的配置环境加载...%v", err)
+	}
+	cl, err := New(WithConfigPath(t.TempDir()), WithEnv("test"), WithoutLogFile(), WithLogOutput(ioutil.Discard, All))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	c, err = cl.LoadConfig("comm.app", source)
+	if err != nil || c.Get("debug").Bool() {
+		t.Errorf("应以客户端的配置环境加载...%v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package conf
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 容量单位，以1024进位
+var byteUnits = map[string]uint64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1 << 40,
+	"tib": 1 << 40,
+	"p":   1 << 50,
+	"pb":  1 << 50,
+	"pib": 1 << 50,
+}
+
+// Bytes 以字节数返回容量配置值，与 ByteSize 相同
+func (r *Result) Bytes() uint64 {
+	return r.ByteSize()
+}
+
+// BytesE 以字节数返回容量配置值，与 ByteSizeE 相同
+func (r *Result) BytesE() (uint64, error) {
+	return r.ByteSizeE()
+}
+
+// ByteSize 以字节数返回容量配置值，比如 "64MB"、"1GiB"、"512k"，单位不区分大小写且以1024进位，数值即字节数
+func (r *Result) ByteSize() uint64 {
+	n, _ := r.ByteSizeE()
+	return n
+}
+
+// ByteSizeE 以字节数返回容量配置值，配置不存在或无法解析时返回错误
+func (r *Result) ByteSizeE() (uint64, error) {
+	if !r.isExistence {
+		return 0, r.convertError("byte size", ErrNotFound)
+	}
+	switch r.dataType {
+	case Int, Uint, Float:
+		return r.UintE()
+	case String:
+		v, ok := r.value.(string)
+		if !ok {
+			break
+		}
+		n, err := parseByteSize(v)
+		if err != nil {
+			return 0, r.convertError("byte size", err)
+		}
+		return n, nil
+	}
+	return 0, r.convertError("byte size", nil)
+}
+
+// 解析容量字符串
+func parseByteSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(c rune) bool {
+		return !unicode.IsDigit(c) && c != '.'
+	})
+	if i == -1 {
+		i = len(s)
+	}
+	if i == 0 {
+		return 0, errors.New("缺少数值")
+	}
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, errors.New("无法识别的容量单位:" + s[i:])
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, err
+	}
+	size := n * float64(unit)
+	if size >= math.MaxUint64 {
+		return 0, errOverflow
+	}
+	return uint64(size), nil
+}
+
+// URL 以*url.URL返回配置值，无法解析时返回nil
+func (r *Result) URL() *url.URL {
+	u, _ := r.URLE()
+	return u
+}
+
+// URLE 以*url.URL返回配置值，配置值必须是包含协议的完整地址，配置不存在或无法解析时返回错误
+func (r *Result) URLE() (*url.URL, error) {
+	if !r.isExistence {
+		return nil, r.convertError("*url.URL", ErrNotFound)
+	}
+	v, ok := r.value.(string)
+	if r.dataType != String || !ok {
+		return nil, r.convertError("*url.URL", nil)
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return nil, r.convertError("*url.URL", err)
+	}
+	if u.Scheme == "" {
+		return nil, r.convertError("*url.URL", errors.New("缺少协议"))
+	}
+	return u, nil
+}
+
+// IP 以net.IP返回配置值，无法解析时返回nil
+func (r *Result) IP() net.IP {
+	ip, _ := r.IPE()
+	return ip
+}
+
+// IPE 以net.IP返回配置值，支持IPv4和IPv6，配置不存在或无法解析时返回错误
+func (r *Result) IPE() (net.IP, error) {
+	if !r.isExistence {
+		return nil, r.convertError("net.IP", ErrNotFound)
+	}
+	v, ok := r.value.(string)
+	if r.dataType != String || !ok {
+		return nil, r.convertError("net.IP", nil)
+	}
+	ip := net.ParseIP(strings.TrimSpace(v))
+	if ip == nil {
+		return nil, r.convertError("net.IP", errors.New("不是有效的IP地址"))
+	}
+	return ip, nil
+}
+
+// CIDR 以*net.IPNet返回网段配置值，比如 "10.0.0.0/8"，无法解析时返回nil
+func (r *Result) CIDR() *net.IPNet {
+	n, _ := r.CIDRE()
+	return n
+}
+
+// CIDRE 以*net.IPNet返回网段配置值，配置不存在或无法解析时返回错误
+func (r *Result) CIDRE() (*net.IPNet, error) {
+	if !r.isExistence {
+		return nil, r.convertError("*net.IPNet", ErrNotFound)
+	}
+	v, ok := r.value.(string)
+	if r.dataType != String || !ok {
+		return nil, r.convertError("*net.IPNet", nil)
+	}
+	_, n, err := net.ParseCIDR(strings.TrimSpace(v))
+	if err != nil {
+		return nil, r.convertError("*net.IPNet", err)
+	}
+	return n, nil
+}
+
+// 以切片元素返回配置值，数组直接展开，字符串以逗号分隔(便于xdiamond配置中心这样只有字符串值的配置源配置列表)，其他配置值作为单个元素
+func (r *Result) items() []interface{} {
+	if !r.isExistence || r.value == nil {
+		return make([]interface{}, 0)
+	}
+	switch v := r.value.(type) {
+	case []interface{}:
+		return v
+	case string:
+		items := make([]interface{}, 0)
+		for _, item := range strings.Split(v, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	rv := reflect.ValueOf(r.value)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		items := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, rv.Index(i).Interface())
+		}
+		return items
+	}
+	return []interface{}{r.value}
+}
+
+// StringSlice 以字符串切片返回配置值，字符串配置值以逗号分隔，比如 "a, b" 返回 [a b]
+func (r *Result) StringSlice() []string {
+	items := r.items()
+	v := make([]string, 0, len(items))
+	for _, item := range items {
+		itemResult := genResult(item)
+		v = append(v, itemResult.String())
+	}
+	return v
+}
+
+// IntSlice 以int64切片返回配置值，有元素无法转换时返回空切片
+func (r *Result) IntSlice() []int64 {
+	v, err := r.IntSliceE()
+	if err != nil {
+		return make([]int64, 0)
+	}
+	return v
+}
+
+// IntSliceE 以int64切片返回配置值，有元素无法转换时返回错误
+func (r *Result) IntSliceE() ([]int64, error) {
+	items := r.items()
+	v := make([]int64, 0, len(items))
+	for _, item := range items {
+		itemResult := genResult(item)
+		n, err := itemResult.IntE()
+		if err != nil {
+			return nil, err
+		}
+		v = append(v, n)
+	}
+	return v, nil
+}
+
+// FloatSlice 以float64切片返回配置值，有元素无法转换时返回空切片
+func (r *Result) FloatSlice() []float64 {
+	v, err := r.FloatSliceE()
+	if err != nil {
+		return make([]float64, 0)
+	}
+	return v
+}
+
+// FloatSliceE 以float64切片返回配置值，有元素无法转换时返回错误
+func (r *Result) FloatSliceE() ([]float64, error) {
+	items := r.items()
+	v := make([]float64, 0, len(items))
+	for _, item := range items {
+		itemResult := genResult(item)
+		f, err := itemResult.FloatE()
+		if err != nil {
+			return nil, err
+		}
+		v = append(v, f)
+	}
+	return v, nil
+}
+
+// BoolSlice 以布尔切片返回配置值，有元素无法转换时返回空切片
+func (r *Result) BoolSlice() []bool {
+	v, err := r.BoolSliceE()
+	if err != nil {
+		return make([]bool, 0)
+	}
+	return v
+}
+
+// BoolSliceE 以布尔切片返回配置值，有元素无法转换时返回错误
+func (r *Result) BoolSliceE() ([]bool, error) {
+	items := r.items()
+	v := make([]bool, 0, len(items))
+	for _, item := range items {
+		itemResult := genResult(item)
+		b, err := itemResult.BoolE()
+		if err != nil {
+			return nil, err
+		}
+		v = append(v, b)
+	}
+	return v, nil
+}
+
+// StringMap 以map[string]string返回配置值，支持表类型的配置值以及 "k1=v1,k2=v2" 这样的字符串配置值
+// 注意toml中的表会被展开为多个配置键，此时请使用 (*ConfigObject).StringMap
+func (r *Result) StringMap() map[string]string {
+	v := make(map[string]string)
+	if !r.isExistence {
+		return v
+	}
+	switch m := r.value.(type) {
+	case map[string]interface{}:
+		for k, item := range m {
+			itemResult := genResult(item)
+			v[k] = itemResult.String()
+		}
+	case string:
+		for _, item := range strings.Split(m, ",") {
+			kv := strings.SplitN(item, "=", 2)
+			key := strings.TrimSpace(kv[0])
+			if key == "" {
+				continue
+			}
+			if len(kv) == 2 {
+				v[key] = strings.TrimSpace(kv[1])
+			} else {
+				v[key] = ""
+			}
+		}
+	}
+	return v
+}
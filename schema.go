@@ -0,0 +1,369 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// tagRequired 结构体标签，"true" 表示配置键必须存在
+	tagRequired = "required"
+	// tagMin 结构体标签，数值的最小值，字符串、数组的最小长度
+	tagMin = "min"
+	// tagMax 结构体标签，数值的最大值，字符串、数组的最大长度
+	tagMax = "max"
+	// tagEnum 结构体标签，以逗号分隔的可选值
+	tagEnum = "enum"
+	// tagPattern 结构体标签，配置值需匹配的正则表达式
+	tagPattern = "pattern"
+)
+
+// Schema 配置声明，声明配置对象应包含的配置键及其约束
+type Schema []Field
+
+// Field 配置键声明
+type Field struct {
+	// Key 配置键
+	Key string
+	// Type 配置值类型，配置值需能转换为此类型，String 以及 Undefined 不检查类型
+	Type confType
+	// Required 是否必须存在，有默认值时以默认值校验
+	Required bool
+	// Default 配置不存在时的默认值，为nil时没有默认值
+	Default interface{}
+	// Min Max 数值的取值范围，字符串、数组的长度范围，为nil时不限制
+	Min interface{}
+	Max interface{}
+	// Enum 可选值，以字符串形式比对
+	Enum []interface{}
+	// Pattern 配置值需匹配的正则表达式
+	Pattern string
+	// Check 自定义校验，比如 (*Result).DurationE
+	Check func(r *Result) error
+}
+
+// Violation 不符合配置声明的配置键
+type Violation struct {
+	// Key 配置键
+	Key string
+	// Err 原因
+	Err error
+}
+
+// SchemaError 配置校验报告，包含全部不符合配置声明的配置键，errors.Is(err, ErrValidation) 为true
+type SchemaError struct {
+	// Violations 按配置声明顺序排列
+	Violations []Violation
+}
+
+// Error 实现error接口
+func (e *SchemaError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		// 类型转换错误已包含配置键
+		if ce, ok := v.Err.(*ConvertError); ok && ce.Key == v.Key {
+			msgs = append(msgs, v.Err.Error())
+			continue
+		}
+		msgs = append(msgs, "配置["+v.Key+"]:"+v.Err.Error())
+	}
+	return fmt.Sprintf("%d个配置键不符合配置声明:%s", len(e.Violations), strings.Join(msgs, ";"))
+}
+
+// Is 判断错误类别
+func (e *SchemaError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Validate 以配置声明校验配置对象，返回包含全部不符合声明的配置键的 *SchemaError，全部符合时返回nil
+func (c *ConfigObject) Validate(s Schema) error {
+	report := new(SchemaError)
+	for _, f := range s {
+		r := c.Get(f.Key)
+		if !r.Exists() && f.Default != nil {
+			def := genResult(f.Default)
+			def.key = f.Key
+			r = &def
+		}
+		if !r.Exists() {
+			if f.Required {
+				report.Violations = append(report.Violations, Violation{f.Key, ErrNotFound})
+			}
+			continue
+		}
+		err := f.check(r)
+		if err != nil {
+			report.Violations = append(report.Violations, Violation{f.Key, err})
+		}
+	}
+	if len(report.Violations) > 0 {
+		return report
+	}
+	return nil
+}
+
+// RegisterSchema 为指定配置标志注册配置声明，配置加载以及更新时先以默认值填充缺失的配置键(在全部校验函数之前)再校验，
+// 校验不通过时与 RegisterValidator 一致，NewConfig 中断程序执行并输出全部不符合声明的配置键
+func (cl *Client) RegisterSchema(fileName string, s Schema) {
+	cl.mutex.Lock()
+	cl.schemas[fileName] = append(cl.schemas[fileName], s)
+	cl.mutex.Unlock()
+	cl.RegisterValidator(fileName, func(co *ConfigObject) error {
+		return co.Validate(s)
+	})
+}
+
+// 以配置标志的全部配置声明的默认值填充缺失的配置键，只用于尚未生效的配置对象
+func (cl *Client) setDefaults(fileName string, co *ConfigObject) {
+	cl.mutex.RLock()
+	schemas := append([]Schema(nil), cl.schemas[fileName]...)
+	cl.mutex.RUnlock()
+	for _, s := range schemas {
+		s.setDefaults(co)
+	}
+}
+
+// 以默认值填充缺失的配置键
+func (s Schema) setDefaults(co *ConfigObject) {
+	for _, f := range s {
+		if _, ok := co.data[f.Key]; !ok && f.Default != nil {
+			co.data[f.Key] = genResult(f.Default)
+		}
+	}
+}
+
+// 校验配置值
+func (f *Field) check(r *Result) error {
+	err := checkType(r, f.Type)
+	if err != nil {
+		return err
+	}
+	if f.Min != nil || f.Max != nil {
+		err = f.checkRange(r)
+		if err != nil {
+			return err
+		}
+	}
+	if len(f.Enum) > 0 {
+		ok := false
+		for _, e := range f.Enum {
+			if fmt.Sprintf("%v", e) == r.String() {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%v 不是可选值 %v", r.value, f.Enum)
+		}
+	}
+	if f.Pattern != "" {
+		re, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return errors.New("正则表达式错误:" + err.Error())
+		}
+		if !re.MatchString(r.String()) {
+			return fmt.Errorf("%v 不匹配 %s", r.value, f.Pattern)
+		}
+	}
+	if f.Check != nil {
+		return f.Check(r)
+	}
+	return nil
+}
+
+// 校验配置值类型
+func checkType(r *Result, t confType) error {
+	var err error
+	switch t {
+	case Int:
+		_, err = r.IntE()
+	case Uint:
+		_, err = r.UintE()
+	case Float:
+		_, err = r.FloatE()
+	case Bool:
+		_, err = r.BoolE()
+	case Time:
+		_, err = r.TimeE()
+	case Array:
+		if r.dataType != Array && r.dataType != String {
+			err = r.convertError("array", errors.New("不是数组"))
+		}
+	}
+	return err
+}
+
+// 校验取值范围，字符串以及数组校验长度
+func (f *Field) checkRange(r *Result) error {
+	var n float64
+	var what string
+	switch {
+	case f.Type == Array || r.dataType == Array:
+		n, what = float64(len(r.items())), "长度"
+	case r.dataType == String && (f.Type == String || f.Type == Undefined):
+		n, what = float64(utf8.RuneCountInString(r.String())), "长度"
+	default:
+		v, err := r.FloatE()
+		if err != nil {
+			return err
+		}
+		n, what = v, "取值"
+	}
+	if f.Min != nil {
+		min, err := toFloat64(f.Min)
+		if err != nil {
+			return errors.New("最小值声明错误:" + err.Error())
+		}
+		if n < min {
+			return fmt.Errorf("%s%v小于%v", what, n, f.Min)
+		}
+	}
+	if f.Max != nil {
+		max, err := toFloat64(f.Max)
+		if err != nil {
+			return errors.New("最大值声明错误:" + err.Error())
+		}
+		if n > max {
+			return fmt.Errorf("%s%v大于%v", what, n, f.Max)
+		}
+	}
+	return nil
+}
+
+// SchemaFromStruct 以结构体标签生成配置声明，v 为结构体或结构体指针，配置键规则与 Unmarshal 一致，例如:
+/**
+type Server struct {
+	Addr    string        `conf:"addr" required:"true" pattern:"^[\\w.]+:\\d+$"`
+	Mode    string        `conf:"mode" default:"release" enum:"debug,release"`
+	Workers int           `conf:"workers" min:"1" max:"64"`
+	Timeout time.Duration `conf:"timeout" default:"5s"`
+}
+**/
+// 配置值类型由字段类型决定，time.Duration、url.URL、net.IP、net.IPNet 字段校验能否解析，map 字段不声明
+func SchemaFromStruct(v interface{}) (Schema, error) {
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil, errors.New("SchemaFromStruct 需要一个结构体,当前类型:" + fmt.Sprintf("%T", v))
+	}
+	s := make(Schema, 0)
+	err := appendFields(&s, "", rt)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// 按字段生成配置键声明
+func appendFields(s *Schema, prefix string, rt reflect.Type) error {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		key, ok := fieldKey(prefix, field)
+		if !ok {
+			continue
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Map {
+			continue
+		}
+		if ft.Kind() == reflect.Struct && ft != timeType && ft != urlType && ft != ipNetType {
+			err := appendFields(s, key, ft)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		f, err := fieldFromTag(key, ft, field.Tag)
+		if err != nil {
+			return err
+		}
+		*s = append(*s, f)
+	}
+	return nil
+}
+
+// 以字段类型以及标签生成配置键声明
+func fieldFromTag(key string, ft reflect.Type, tag reflect.StructTag) (Field, error) {
+	f := Field{Key: key, Type: Undefined}
+	switch ft {
+	case timeType:
+		f.Type = Time
+	case durationType:
+		f.Check = func(r *Result) error {
+			_, err := r.DurationE()
+			return err
+		}
+	case urlType:
+		f.Check = func(r *Result) error {
+			_, err := r.URLE()
+			return err
+		}
+	case ipType:
+		f.Check = func(r *Result) error {
+			_, err := r.IPE()
+			return err
+		}
+	case ipNetType:
+		f.Check = func(r *Result) error {
+			_, err := r.CIDRE()
+			return err
+		}
+	default:
+		switch ft.Kind() {
+		case reflect.String:
+			f.Type = String
+		case reflect.Bool:
+			f.Type = Bool
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f.Type = Int
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			f.Type = Uint
+		case reflect.Float32, reflect.Float64:
+			f.Type = Float
+		case reflect.Slice, reflect.Array:
+			f.Type = Array
+		}
+	}
+	f.Required = tag.Get(tagRequired) == "true"
+	if def, ok := tag.Lookup(tagDefault); ok {
+		f.Default = def
+	}
+	for _, name := range []string{tagMin, tagMax} {
+		s, ok := tag.Lookup(name)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return f, errors.New("配置[" + key + "]:" + name + " 标签必须为数值")
+		}
+		if name == tagMin {
+			f.Min = n
+		} else {
+			f.Max = n
+		}
+	}
+	if enum, ok := tag.Lookup(tagEnum); ok {
+		for _, e := range strings.Split(enum, ",") {
+			f.Enum = append(f.Enum, strings.TrimSpace(e))
+		}
+	}
+	if pattern, ok := tag.Lookup(tagPattern); ok {
+		_, err := regexp.Compile(pattern)
+		if err != nil {
+			return f, errors.New("配置[" + key + "]:正则表达式错误:" + err.Error())
+		}
+		f.Pattern = pattern
+	}
+	return f, nil
+}
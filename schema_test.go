@@ -0,0 +1,91 @@
+package conf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type schemaApp struct {
+	Name   string `conf:"name" required:"true" pattern:"^[a-z]+$"`
+	Mode   string `conf:"mode" default:"release" enum:"debug,release"`
+	Server struct {
+		Port    int           `conf:"port" required:"true" min:"1" max:"65535"`
+		Timeout time.Duration `conf:"timeout" default:"5s"`
+	} `conf:"server"`
+	Tags  []string          `conf:"tags" max:"2"`
+	Extra map[string]string `conf:"extra"`
+}
+
+func TestSchema(t *testing.T) {
+	s, err := SchemaFromStruct(&schemaApp{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 5 || s[2].Key != "server.port" || s[2].Type != Int || !s[2].Required {
+		t.Fatalf("配置声明生成错误...%+v", s)
+	}
+	c := newTestObject(t, `
+	name = "Web"
+	mode = "test"
+	tags = ["a", "b", "c"]
+	[server]
+	port = 0
+	timeout = "5x"
+	`)
+	err = c.Validate(s)
+	var report *SchemaError
+	if !errors.As(err, &report) || !errors.Is(err, ErrValidation) {
+		t.Fatalf("校验应返回 *SchemaError...%v", err)
+	}
+	keys := make([]string, 0)
+	for _, v := range report.Violations {
+		keys = append(keys, v.Key)
+	}
+	if len(keys) != 5 || keys[0] != "name" || keys[4] != "tags" {
+		t.Errorf("校验报告应包含全部不符合声明的配置键...%v", err)
+	}
+	c = newTestObject(t, `
+	name = "web"
+	[server]
+	port = 8080
+	`)
+	err = c.Validate(s)
+	if err != nil {
+		t.Error(err)
+	}
+	err = c.Validate(Schema{{Key: "server.host", Required: true}, {Key: "server.port", Type: Int, Enum: []interface{}{80, 443}}})
+	if report, ok := err.(*SchemaError); !ok || len(report.Violations) != 2 || !errors.Is(report.Violations[0].Err, ErrNotFound) {
+		t.Errorf("校验报告错误...%v", err)
+	}
+}
+
+func TestRegisterSchema(t *testing.T) {
+	cl := newTestClient(t)
+	s, err := SchemaFromStruct(schemaApp{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 先注册的校验函数同样能看到默认值
+	cl.RegisterValidator("comm.schema", func(co *ConfigObject) error {
+		if !co.Get("mode").Exists() {
+			return errors.New("缺少默认值")
+		}
+		return nil
+	})
+	cl.RegisterSchema("comm.schema", s)
+	writeTestFile(t, cl, "comm/schema.toml", "name = \"web\"")
+	_, err = cl.LoadConfig("comm.schema", SourceFile)
+	if !errors.Is(err, ErrValidation) || strings.Contains(err.Error(), "缺少默认值") {
+		t.Fatalf("缺少必填配置键时加载应失败...%v", err)
+	}
+	writeTestFile(t, cl, "comm/schema.toml", "name = \"web\"\n[server]\nport = 80")
+	c, err := cl.LoadConfig("comm.schema", SourceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Get("mode").String() != "release" || c.Get("server.timeout").Duration() != 5*time.Second {
+		t.Errorf("缺失的配置键应以默认值填充...%v", c.All())
+	}
+}
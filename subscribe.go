@@ -0,0 +1,172 @@
+package conf
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Changes 通道的缓冲大小
+const changesBuffer = 16
+
+// Change 配置值变更，配置键新增时 Old.Exists() 为false，删除时 New.Exists() 为false
+type Change struct {
+	// Key 配置键，相对于订阅时的配置对象
+	Key string
+	// Old 变更前的配置值
+	Old *Result
+	// New 变更后的配置值
+	New *Result
+}
+
+// 配置变更订阅
+type subscription struct {
+	// 完整配置键或者配置键前缀
+	key string
+	// 是否以前缀匹配
+	prefix bool
+	// 订阅时的配置对象前缀，回调时去掉此前缀
+	base string
+	fn   func(key string, old *Result, new *Result)
+}
+
+// 配置变更订阅列表，以配置标志区分
+type subscriptions struct {
+	mutex sync.RWMutex
+	id    uint64
+	subs  map[string]map[uint64]*subscription
+}
+
+// Watch 订阅指定配置键的变更，返回取消订阅的函数，同一配置对象可以有多个订阅;
+// key 为表时(比如 db.master)表下任意配置键变更都会回调，old、new 为变更的配置键的值，需要区分配置键时使用 WatchPrefix
+func (c *ConfigObject) Watch(key string, fn func(old *Result, new *Result)) (cancel func()) {
+	return c.subscribe(joinKey(c.prefix, key), false, func(_ string, old *Result, new *Result) {
+		fn(old, new)
+	})
+}
+
+// WatchPrefix 订阅以指定前缀开头的全部配置键的变更，比如 WatchPrefix("feature.", fn)，返回取消订阅的函数
+func (c *ConfigObject) WatchPrefix(prefix string, fn func(key string, old *Result, new *Result)) (cancel func()) {
+	if c.prefix != "" {
+		prefix = c.prefix + "." + prefix
+	}
+	return c.subscribe(prefix, true, fn)
+}
+
+// Changes 以通道接收以指定前缀开头的配置键的变更，ctx 结束时取消订阅并关闭通道;
+// 通道已满(没有及时读取)时丢弃变更并记录日志，不阻塞其他回调
+func (c *ConfigObject) Changes(ctx context.Context, prefix string) <-chan Change {
+	ch := make(chan Change, changesBuffer)
+	var mutex sync.Mutex
+	closed := false
+	cancel := c.WatchPrefix(prefix, func(key string, old *Result, new *Result) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- Change{key, old, new}:
+		default:
+			c.client.log.Warning("配置", c.fileName, "变更通道已满,丢弃配置键", key, "的变更")
+		}
+	})
+	go func() {
+		<-ctx.Done()
+		cancel()
+		mutex.Lock()
+		closed = true
+		close(ch)
+		mutex.Unlock()
+	}()
+	return ch
+}
+
+// 添加订阅，配置对象不属于任何客户端时订阅不会生效
+func (c *ConfigObject) subscribe(key string, prefix bool, fn func(key string, old *Result, new *Result)) func() {
+	if c.client == nil {
+		return func() {}
+	}
+	return c.client.subscriptions.add(c.fileName, &subscription{key, prefix, c.prefix, fn})
+}
+
+// 添加订阅，返回取消订阅的函数
+func (s *subscriptions) add(fileName string, sub *subscription) func() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.subs == nil {
+		s.subs = make(map[string]map[uint64]*subscription)
+	}
+	if s.subs[fileName] == nil {
+		s.subs[fileName] = make(map[uint64]*subscription)
+	}
+	s.id++
+	id := s.id
+	s.subs[fileName][id] = sub
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		delete(s.subs[fileName], id)
+	}
+}
+
+// 获取指定配置标志的全部订阅
+func (s *subscriptions) get(fileName string) []*subscription {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	ids := make([]uint64, 0, len(s.subs[fileName]))
+	for id := range s.subs[fileName] {
+		ids = append(ids, id)
+	}
+	// 按订阅顺序回调
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	subs := make([]*subscription, 0, len(ids))
+	for _, id := range ids {
+		subs = append(subs, s.subs[fileName][id])
+	}
+	return subs
+}
+
+// 以配置变更内容通知订阅者，call 负责调用订阅者的回调函数
+func notify(subs []*subscription, fileName string, cs *ChangeSet, call func(fileName string, fn func())) {
+	if len(subs) == 0 {
+		return
+	}
+	old := cs.Old
+	if old == nil {
+		old = new(ConfigObject)
+	}
+	for _, keys := range [][]string{cs.Added, cs.Removed, cs.Modified} {
+		for _, key := range keys {
+			for _, sub := range subs {
+				if !sub.match(key) {
+					continue
+				}
+				sub, key := sub, key
+				call(fileName, func() {
+					sub.fn(sub.relative(key), old.Get(key), cs.New.Get(key))
+				})
+			}
+		}
+	}
+}
+
+// 判断配置键是否匹配此订阅
+func (sub *subscription) match(key string) bool {
+	if sub.prefix {
+		return strings.HasPrefix(key, sub.key)
+	}
+	// 订阅的配置键为表时匹配表下的配置键
+	return key == sub.key || strings.HasPrefix(key, sub.key+".")
+}
+
+// 去掉订阅时配置对象的前缀
+func (sub *subscription) relative(key string) string {
+	if sub.base == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, sub.base+".")
+}
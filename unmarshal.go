@@ -0,0 +1,271 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	// tagKey 结构体标签，指定字段对应的配置键，"-" 表示忽略此字段
+	tagKey = "conf"
+	// tagDefault 结构体标签，指定配置不存在时的默认值
+	tagDefault = "default"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	urlType      = reflect.TypeOf(url.URL{})
+	ipType       = reflect.TypeOf(net.IP{})
+	ipNetType    = reflect.TypeOf(net.IPNet{})
+)
+
+// Unmarshal 以结构体标签填充结构体，v 必须为结构体指针，例如:
+/**
+type App struct {
+	Title string `conf:"title"`
+	Base  struct {
+		Int   int     `conf:"int" default:"1"`
+		Float float64 `conf:"float"`
+	} `conf:"base"`
+	Servers map[string]struct {
+		IP string `conf:"ip"`
+	} `conf:"servers"`
+}
+**/
+// 嵌套结构体以其配置键为前缀，未指定 conf 标签时以小写的字段名作为配置键，类型转换规则与 Int Float Bool Time 等方法一致
+func (c *ConfigObject) Unmarshal(v interface{}) error {
+	return c.UnmarshalKey("", v)
+}
+
+// UnmarshalKey 以指定配置键为前缀填充 v，v 为结构体指针时按字段填充，否则以此配置键的配置值填充
+func (c *ConfigObject) UnmarshalKey(prefix string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("Unmarshal 需要一个非空指针,当前类型:" + fmt.Sprintf("%T", v))
+	}
+	return c.decode(prefix, rv.Elem(), "", false)
+}
+
+// 以配置键填充一个值
+func (c *ConfigObject) decode(key string, rv reflect.Value, def string, hasDef bool) error {
+	switch {
+	case rv.Kind() == reflect.Struct && rv.Type() != timeType && rv.Type() != urlType && rv.Type() != ipNetType:
+		return c.decodeStruct(key, rv)
+	case rv.Kind() == reflect.Map:
+		return c.decodeMap(key, rv)
+	case rv.Kind() == reflect.Ptr:
+		_, ok := c.lookup(key)
+		if !ok && !hasDef && !c.hasChildren(key) {
+			return nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return c.decode(key, rv.Elem(), def, hasDef)
+	}
+	r, ok := c.lookup(key)
+	if !ok {
+		if !hasDef {
+			return nil
+		}
+		r = genResult(def)
+	}
+	err := setValue(rv, &r)
+	if ce, ok := err.(*ConvertError); ok {
+		ce.Key = key
+	}
+	return err
+}
+
+// 按字段填充结构体
+func (c *ConfigObject) decodeStruct(prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		key, ok := fieldKey(prefix, field)
+		if !ok {
+			continue
+		}
+		def, hasDef := field.Tag.Lookup(tagDefault)
+		err := c.decode(key, rv.Field(i), def, hasDef)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 获取字段对应的配置键，忽略的字段返回false
+func fieldKey(prefix string, field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" && !(field.Anonymous && field.Type.Kind() == reflect.Struct) {
+		return "", false
+	}
+	name, ok := field.Tag.Lookup(tagKey)
+	if name == "-" {
+		return "", false
+	}
+	// 未指定配置键的匿名结构体字段展开到当前层级
+	if field.Anonymous && !ok {
+		return prefix, true
+	}
+	if !ok {
+		name = strings.ToLower(field.Name)
+	}
+	return joinKey(prefix, name), true
+}
+
+// 以子节点填充 map，map 的键为子节点名称
+func (c *ConfigObject) decodeMap(prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	if rt.Key().Kind() != reflect.String {
+		return errors.New("配置[" + prefix + "]:map 的键必须为字符串类型")
+	}
+	children := c.Children(prefix)
+	if len(children) == 0 {
+		return nil
+	}
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMap(rt))
+	}
+	for _, child := range children {
+		elem := reflect.New(rt.Elem()).Elem()
+		err := c.decode(joinKey(prefix, child), elem, "", false)
+		if err != nil {
+			return err
+		}
+		rv.SetMapIndex(reflect.ValueOf(child).Convert(rt.Key()), elem)
+	}
+	return nil
+}
+
+// 拼接配置键
+func joinKey(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// 以配置值填充基本类型、时间以及切片
+func setValue(rv reflect.Value, r *Result) error {
+	if rv.Type() == timeType {
+		t, err := r.TimeE()
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch rv.Type() {
+	case urlType:
+		u, err := r.URLE()
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(*u))
+		return nil
+	case ipType:
+		ip, err := r.IPE()
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(ip))
+		return nil
+	case ipNetType:
+		n, err := r.CIDRE()
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(*n))
+		return nil
+	}
+	if rv.Type() == durationType {
+		d, err := r.DurationE()
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(d))
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(r.String())
+	case reflect.Bool:
+		b, err := r.BoolE()
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := r.IntE()
+		if err != nil {
+			return err
+		}
+		if rv.OverflowInt(n) {
+			return r.convertError(rv.Type().String(), errors.New("数值溢出"))
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := r.UintE()
+		if err != nil {
+			return err
+		}
+		if rv.OverflowUint(n) {
+			return r.convertError(rv.Type().String(), errors.New("数值溢出"))
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := r.FloatE()
+		if err != nil {
+			return err
+		}
+		if rv.OverflowFloat(f) {
+			return r.convertError(rv.Type().String(), errors.New("数值溢出"))
+		}
+		rv.SetFloat(f)
+	case reflect.Slice:
+		return setSlice(rv, r)
+	case reflect.Interface:
+		if r.value != nil {
+			rv.Set(reflect.ValueOf(r.value))
+		}
+	default:
+		return r.convertError(rv.Type().String(), errors.New("不支持的字段类型"))
+	}
+	return nil
+}
+
+// 以数组配置值填充切片，数组元素为表时按结构体或map填充，字符串配置值以逗号分隔
+func setSlice(rv reflect.Value, r *Result) error {
+	items := r.items()
+	slice := reflect.MakeSlice(rv.Type(), len(items), len(items))
+	for i, item := range items {
+		elem := slice.Index(i)
+		if m, ok := item.(map[string]interface{}); ok {
+			kvMap := make(map[string]Result)
+			err := setKvMap(m, make(confKeys, 0), kvMap)
+			if err != nil {
+				return err
+			}
+			tmp := &ConfigObject{data: kvMap, isExistence: true}
+			err = tmp.decode("", elem, "", false)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		itemResult := genResult(item)
+		err := setValue(elem, &itemResult)
+		if err != nil {
+			return err
+		}
+	}
+	rv.Set(slice)
+	return nil
+}
@@ -0,0 +1,82 @@
+package conf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnmarshal(t *testing.T) {
+	c := newTestObject(t, `
+	title = "TOML Example"
+	ports = [8001, 8002]
+	[base]
+	dob = 2018-05-27T07:32:00Z
+	int = 1
+	float = 1.1
+	bool = true
+	[servers.alpha]
+	ip = "10.0.0.1"
+	[servers.beta]
+	ip = "10.0.0.2"
+	[[slave]]
+	addr = "localhost:6379"
+	db = 1
+	[[slave]]
+	addr = "localhost:6380"
+	db = 2
+	`)
+	type server struct {
+		IP string `conf:"ip"`
+	}
+	var v struct {
+		Title string  `conf:"title"`
+		Ports []int   `conf:"ports"`
+		Name  string  `conf:"name" default:"app"`
+		Port  *uint16 `conf:"port" default:"80"`
+		Base  struct {
+			Dob   time.Time `conf:"dob"`
+			Int   int8      `conf:"int"`
+			Float float32   `conf:"float"`
+			Bool  bool
+		} `conf:"base"`
+		Servers map[string]server `conf:"servers"`
+		Slave   []struct {
+			Addr string `conf:"addr"`
+			DB   int    `conf:"db"`
+		} `conf:"slave"`
+		Ignore string `conf:"-"`
+	}
+	err := c.Unmarshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Title != "TOML Example" || v.Name != "app" || v.Port == nil || *v.Port != 80 {
+		t.Errorf("基本类型及默认值填充错误...%+v", v)
+	}
+	if len(v.Ports) != 2 || v.Ports[1] != 8002 {
+		t.Errorf("数组填充错误...%v", v.Ports)
+	}
+	if ti, _ := time.Parse(time.RFC3339, "2018-05-27T07:32:00Z"); v.Base.Dob != ti || v.Base.Int != 1 || !v.Base.Bool {
+		t.Errorf("嵌套结构体填充错误...%+v", v.Base)
+	}
+	if len(v.Servers) != 2 || v.Servers["beta"].IP != "10.0.0.2" {
+		t.Errorf("map填充错误...%+v", v.Servers)
+	}
+	if len(v.Slave) != 2 || v.Slave[1].Addr != "localhost:6380" || v.Slave[1].DB != 2 {
+		t.Errorf("表数组填充错误...%+v", v.Slave)
+	}
+
+	var ip string
+	err = c.UnmarshalKey("servers.alpha.ip", &ip)
+	if err != nil || ip != "10.0.0.1" {
+		t.Errorf("UnmarshalKey填充错误...%v %v", ip, err)
+	}
+	var bad struct {
+		Title int `conf:"title"`
+	}
+	err = c.Unmarshal(&bad)
+	ce, ok := err.(*ConvertError)
+	if !ok || ce.Key != "title" {
+		t.Errorf("类型不匹配时应返回ConvertError...%v", err)
+	}
+}
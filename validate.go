@@ -0,0 +1,47 @@
+package conf
+
+// Validator 配置校验函数，配置生效之前调用，返回错误时拒绝此次配置更新
+type Validator func(co *ConfigObject) error
+
+// ErrorHandler 配置更新失败(包括校验不通过)时调用此方法，原配置继续生效
+type ErrorHandler interface {
+	ErrorHandel(fileName string, err error)
+}
+
+// RegisterValidator 为指定配置标志注册校验函数，可以注册多个，按注册顺序校验，对之后的配置加载以及配置更新生效。
+// 校验不通过时原配置继续生效、不覆盖本地备份，首次加载时 LoadConfig 返回错误
+func (cl *Client) RegisterValidator(fileName string, v Validator) {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	cl.validators[fileName] = append(cl.validators[fileName], v)
+}
+
+// AddErrorHandler 添加一个配置更新失败回调函数，可以添加多个，按添加顺序调用
+func (cl *Client) AddErrorHandler(handel ErrorHandler) {
+	cl.dispatcher.addErrorHandler(handel)
+}
+
+// RemoveErrorHandler 删除配置更新失败回调函数，回调函数需为可比较的类型(比如指针)
+func (cl *Client) RemoveErrorHandler(handel ErrorHandler) {
+	cl.dispatcher.removeErrorHandler(handel)
+}
+
+// 依次调用配置标志的校验函数，任意一个不通过即返回
+func (cl *Client) validate(fileName string, source Source, co *ConfigObject) error {
+	cl.mutex.RLock()
+	validators := append([]Validator(nil), cl.validators[fileName]...)
+	cl.mutex.RUnlock()
+	for _, v := range validators {
+		err := v(co)
+		if err != nil {
+			return newError(ErrValidation, fileName, source, err)
+		}
+	}
+	return nil
+}
+
+// 配置更新失败，记录日志并异步调用配置更新失败回调函数
+func (cl *Client) reject(fileName string, err error) {
+	cl.log.Error("配置", fileName, "更新失败,保留原配置:", err)
+	cl.dispatcher.dispatch(&event{fileName: fileName, err: err})
+}
@@ -0,0 +1,102 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//以通道接收配置更新失败回调
+type chanError chan error
+
+func (c chanError) ErrorHandel(fileName string, err error) {
+	c <- err
+}
+
+// 端口必须大于0
+func validPort(co *ConfigObject) error {
+	if co.Get("port").Int() <= 0 {
+		return errors.New("端口必须大于0")
+	}
+	return nil
+}
+
+// 等待配置更新失败回调，期间不应有配置回调
+func expectRejected(t *testing.T, errs chanError, cb chanCallback) {
+	t.Helper()
+	select {
+	case e := <-errs:
+		if !errors.Is(e, ErrValidation) {
+			t.Errorf("配置更新失败回调错误...%v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("配置更新失败回调没有被调用...")
+	}
+	select {
+	case <-cb:
+		t.Error("校验不通过时不应回调...")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestValidator(t *testing.T) {
+	errs := make(chanError, 10)
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t, WithErrorHandler(errs), WithCallback(cb), WithFileWatch())
+	cl.RegisterValidator("comm.validate", validPort)
+	writeTestFile(t, cl, "comm/validate.toml", "port = 0")
+	_, err := cl.LoadConfig("comm.validate", SourceFile)
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("首次加载校验不通过应返回错误...%v", err)
+	}
+	writeTestFile(t, cl, "comm/validate.toml", "port = 8080")
+	_, err = cl.LoadConfig("comm.validate", SourceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-cb
+	// 文件变更之后校验不通过
+	writeTestFile(t, cl, "comm/validate.toml", "port = -1")
+	expectRejected(t, errs, cb)
+	co, err := cl.LoadConfig("comm.validate", SourceFile)
+	if err != nil || co.Get("port").Int() != 8080 {
+		t.Errorf("校验不通过时原配置应继续生效...%v", err)
+	}
+}
+
+func TestValidatorPush(t *testing.T) {
+	var port int32 = 8080
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `[{"config":{"key":"port","value":%d}}]`, atomic.LoadInt32(&port))
+	}))
+	defer server.Close()
+	errs := make(chanError, 10)
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t, WithErrorHandler(errs), WithCallback(cb))
+	cl.RegisterValidator("validate.1.0", validPort)
+	writeTestFile(t, cl, "comm/xdiamond.toml", `
+	group_id = "web"
+	http_address = "`+strings.TrimPrefix(server.URL, "http://")+`"
+	poll_interval = "50ms"`)
+	_, err := cl.LoadConfig("validate.1.0", SourceXdaHTTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-cb
+	// 配置中心推送的配置校验不通过
+	atomic.StoreInt32(&port, -1)
+	expectRejected(t, errs, cb)
+	co, err := cl.LoadConfig("validate.1.0", SourceXdaHTTP)
+	if err != nil || co.Get("port").Int() != 8080 {
+		t.Errorf("校验不通过时原配置应继续生效...%v", err)
+	}
+	data, err := cl.backupRecovery("validate.1.0")
+	if err != nil || data["port"] != float64(8080) {
+		t.Errorf("校验不通过时不应覆盖备份...%v %v", data, err)
+	}
+}
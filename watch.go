@@ -0,0 +1,166 @@
+package conf
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// 去抖动间隔，编辑器保存文件时可能分多次写入，间隔内没有新的变更才重新解析
+	watchDebounce = 200 * time.Millisecond
+	// 轮询间隔，系统不支持文件监听时以轮询方式检测文件变更
+	watchPollInterval = 2 * time.Second
+)
+
+// 文件变更通知
+type notifier interface {
+	// set 设置需要监听的文件，文件为完整路径
+	set(files []string) error
+	// events 文件变更时收到文件完整路径
+	events() <-chan string
+	// close 停止监听
+	close()
+}
+
+// 实例化文件变更通知，系统不支持文件监听时以轮询方式检测
+func newNotifier() notifier {
+	n, err := newFsNotifier()
+	if err != nil {
+		return newPollNotifier(watchPollInterval)
+	}
+	return n
+}
+
+// 监听配置文件变更，变更时重新解析配置文件
+func (l *localFile) Watch(ctx context.Context, fileName string, update func(map[string]interface{}, error)) error {
+	n := newNotifier()
+	err := n.set(l.files)
+	if err != nil {
+		n.close()
+		return err
+	}
+	go func() {
+		defer n.close()
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-n.events():
+				debounce = time.After(watchDebounce)
+			case <-debounce:
+				debounce = nil
+				data, err := l.Load(fileName)
+				if err != nil {
+					update(nil, err)
+					continue
+				}
+				// 重新解析之后涉及的文件可能发生变化，无法监听时放弃此次更新，原配置继续生效
+				err = n.set(l.files)
+				if err != nil {
+					update(nil, err)
+					continue
+				}
+				update(data, nil)
+			}
+		}
+	}()
+	return nil
+}
+
+// 文件状态，用于轮询比对
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// 比对文件状态
+func (s fileStamp) equal(o fileStamp) bool {
+	return s.exists == o.exists && s.size == o.size && s.modTime.Equal(o.modTime)
+}
+
+// 获取文件状态
+func getFileStamp(file string) fileStamp {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{info.ModTime(), info.Size(), true}
+}
+
+// 以轮询方式检测文件变更
+type pollNotifier struct {
+	mutex  sync.Mutex
+	files  map[string]fileStamp
+	ch     chan string
+	done   chan struct{}
+	closer sync.Once
+}
+
+// 实例化轮询通知
+func newPollNotifier(interval time.Duration) *pollNotifier {
+	p := &pollNotifier{
+		files: make(map[string]fileStamp),
+		ch:    make(chan string, 1),
+		done:  make(chan struct{}),
+	}
+	go p.poll(interval)
+	return p
+}
+
+// 设置需要监听的文件
+func (p *pollNotifier) set(files []string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	stamps := make(map[string]fileStamp)
+	for _, file := range files {
+		stamp, ok := p.files[file]
+		if !ok {
+			stamp = getFileStamp(file)
+		}
+		stamps[file] = stamp
+	}
+	p.files = stamps
+	return nil
+}
+
+// 文件变更事件
+func (p *pollNotifier) events() <-chan string {
+	return p.ch
+}
+
+// 停止轮询
+func (p *pollNotifier) close() {
+	p.closer.Do(func() {
+		close(p.done)
+	})
+}
+
+// 定时比对文件状态
+func (p *pollNotifier) poll(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.mutex.Lock()
+			for file, stamp := range p.files {
+				current := getFileStamp(file)
+				if current.equal(stamp) {
+					continue
+				}
+				p.files[file] = current
+				select {
+				case p.ch <- file:
+				default:
+				}
+			}
+			p.mutex.Unlock()
+		}
+	}
+}
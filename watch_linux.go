@@ -0,0 +1,148 @@
+package conf
+
+import (
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// 监听目录而不是文件本身，编辑器保存时往往以新文件替换原文件
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_CREATE | syscall.IN_MOVED_TO | syscall.IN_DELETE | syscall.IN_MOVED_FROM
+
+// 基于inotify的文件变更通知
+type inotifyNotifier struct {
+	fd     int
+	epfd   int
+	mutex  sync.Mutex
+	dirs   map[int32]string
+	wds    map[string]int32
+	files  map[string]bool
+	ch     chan string
+	done   chan struct{}
+	exited chan struct{}
+	closer sync.Once
+}
+
+// 实例化inotify文件变更通知
+func newFsNotifier() (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		_ = syscall.Close(fd)
+		return nil, err
+	}
+	event := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+	err = syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, fd, &event)
+	if err != nil {
+		_ = syscall.Close(fd)
+		_ = syscall.Close(epfd)
+		return nil, err
+	}
+	n := &inotifyNotifier{
+		fd:     fd,
+		epfd:   epfd,
+		dirs:   make(map[int32]string),
+		wds:    make(map[string]int32),
+		files:  make(map[string]bool),
+		ch:     make(chan string, 1),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go n.read()
+	return n, nil
+}
+
+// 设置需要监听的文件，以文件所在目录添加监听
+func (n *inotifyNotifier) set(files []string) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.files = make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		file = filepath.Clean(file)
+		n.files[file] = true
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range dirs {
+		if _, ok := n.wds[dir]; ok {
+			continue
+		}
+		wd, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask)
+		if err != nil {
+			return err
+		}
+		n.wds[dir] = int32(wd)
+		n.dirs[int32(wd)] = dir
+	}
+	for dir, wd := range n.wds {
+		if dirs[dir] {
+			continue
+		}
+		_, _ = syscall.InotifyRmWatch(n.fd, uint32(wd))
+		delete(n.wds, dir)
+		delete(n.dirs, wd)
+	}
+	return nil
+}
+
+// 文件变更事件
+func (n *inotifyNotifier) events() <-chan string {
+	return n.ch
+}
+
+// 停止监听
+func (n *inotifyNotifier) close() {
+	n.closer.Do(func() {
+		close(n.done)
+		<-n.exited
+		_ = syscall.Close(n.epfd)
+		_ = syscall.Close(n.fd)
+	})
+}
+
+// 读取inotify事件
+func (n *inotifyNotifier) read() {
+	defer close(n.exited)
+	var buf [syscall.SizeofInotifyEvent * 64]byte
+	events := make([]syscall.EpollEvent, 1)
+	for {
+		select {
+		case <-n.done:
+			return
+		default:
+		}
+		// 超时返回以便检查是否已停止监听
+		ready, err := syscall.EpollWait(n.epfd, events, 500)
+		if err != nil || ready == 0 {
+			continue
+		}
+		length, err := syscall.Read(n.fd, buf[:])
+		if err != nil || length < syscall.SizeofInotifyEvent {
+			continue
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= length; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+			name := string(nameBytes)
+			for len(name) > 0 && name[len(name)-1] == 0 {
+				name = name[:len(name)-1]
+			}
+			n.mutex.Lock()
+			file := filepath.Join(n.dirs[event.Wd], name)
+			watched := n.files[file]
+			n.mutex.Unlock()
+			if !watched {
+				continue
+			}
+			select {
+			case n.ch <- file:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package conf
+
+import "errors"
+
+// 非linux系统暂不支持文件监听，以轮询方式检测文件变更
+func newFsNotifier() (notifier, error) {
+	return nil, errors.New("当前系统不支持文件监听")
+}
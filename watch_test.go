@@ -0,0 +1,182 @@
+package conf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileWatch(t *testing.T) {
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t, WithFileWatch(), WithCallback(cb))
+	writeTestFile(t, cl, "comm/app.toml", `version = 1`)
+	c, err := cl.LoadConfig("comm.app", SourceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-cb
+	if c.Get("version").Int() != 1 {
+		t.Fatal("配置文件读取错误...")
+	}
+	writeTestFile(t, cl, "comm/app.toml", `version = 2`)
+	select {
+	case co := <-cb:
+		if co.Get("version").Int() != 2 {
+			t.Errorf("配置文件变更之后读取错误...%v", co.Get("version").Value())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("配置文件变更之后没有回调...")
+	}
+	c, err = cl.LoadConfig("comm.app", SourceFile)
+	if err != nil || c.Get("version").Int() != 2 {
+		t.Errorf("配置文件变更之后缓存没有更新...%v", err)
+	}
+}
+
+func TestPollNotifier(t *testing.T) {
+	file, err := ioutil.TempFile("", "poll")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	_ = file.Close()
+	p := newPollNotifier(20 * time.Millisecond)
+	defer p.close()
+	_ = p.set([]string{file.Name()})
+	err = ioutil.WriteFile(file.Name(), []byte("changed"), 0664)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case name := <-p.events():
+		if name != file.Name() {
+			t.Errorf("变更文件错误...%v", name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("轮询没有检测到文件变更...")
+	}
+}
+
+func TestXdiamondHTTPPoll(t *testing.T) {
+	var value int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := atomic.LoadInt32(&value)
+		_, _ = fmt.Fprintf(w, `[{"config":{"key":"version","value":"%d"}}]`, v)
+	}))
+	defer server.Close()
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t, WithCallback(cb))
+	writeTestFile(t, cl, "comm/xdiamond.toml", `
+	group_id = "web"
+	http_address = "`+strings.TrimPrefix(server.URL, "http://")+`"
+	poll_interval = "50ms"`)
+	c, err := cl.LoadConfig("poll-test.1.0", SourceXdaHTTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-cb
+	if c.Get("version").Int() != 1 {
+		t.Fatal("配置中心http读取错误...")
+	}
+	// 内容没有变更时不回调
+	select {
+	case <-cb:
+		t.Fatal("配置没有变更时不应回调...")
+	case <-time.After(200 * time.Millisecond):
+	}
+	atomic.StoreInt32(&value, 2)
+	select {
+	case co := <-cb:
+		if co.Get("version").Int() != 2 {
+			t.Errorf("配置变更之后读取错误...%v", co.Get("version").Value())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("配置变更之后没有回调...")
+	}
+	data, err := cl.backupRecovery("poll-test.1.0")
+	if err != nil || data["version"] != "2" {
+		t.Errorf("配置变更之后备份没有更新...%v %v", data, err)
+	}
+}
+
+func TestXdiamondHTTPPollTimeout(t *testing.T) {
+	var value, hang int32 = 1, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&hang) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = fmt.Fprintf(w, `[{"config":{"key":"version","value":"%d"}}]`, atomic.LoadInt32(&value))
+	}))
+	defer server.Close()
+	timeout := httpTimeout
+	httpTimeout = 100 * time.Millisecond
+	defer func() {
+		httpTimeout = timeout
+	}()
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t, WithCallback(cb))
+	writeTestFile(t, cl, "comm/xdiamond.toml", `
+	group_id = "web"
+	http_address = "`+strings.TrimPrefix(server.URL, "http://")+`"
+	poll_interval = "50ms"`)
+	_, err := cl.LoadConfig("poll-timeout.1.0", SourceXdaHTTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-cb
+	// 请求没有响应时超时，之后继续定时拉取
+	atomic.StoreInt32(&hang, 1)
+	time.Sleep(200 * time.Millisecond)
+	atomic.StoreInt32(&value, 2)
+	atomic.StoreInt32(&hang, 0)
+	select {
+	case co := <-cb:
+		if co.Get("version").Int() != 2 {
+			t.Errorf("配置变更之后读取错误...%v", co.Get("version").Value())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("请求超时之后没有继续拉取...")
+	}
+}
+
+func TestXdiamondHTTPPollOutage(t *testing.T) {
+	var down int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprint(w, `[{"config":{"key":"version","value":"1"}}]`)
+	}))
+	defer server.Close()
+	errs := make(chanError, 100)
+	cb := make(chanCallback, 10)
+	cl := newTestClient(t, WithCallback(cb), WithErrorHandler(errs))
+	writeTestFile(t, cl, "comm/xdiamond.toml", `
+	group_id = "web"
+	http_address = "`+strings.TrimPrefix(server.URL, "http://")+`"
+	poll_interval = "20ms"`)
+	_, err := cl.LoadConfig("poll-outage.1.0", SourceXdaHTTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-cb
+	// 配置中心不可用期间只报告一次
+	for i := 0; i < 2; i++ {
+		atomic.StoreInt32(&down, 1)
+		time.Sleep(300 * time.Millisecond)
+		if n := len(errs); n != 1 {
+			t.Fatalf("配置中心不可用期间应只报告一次...%v", n)
+		}
+		<-errs
+		atomic.StoreInt32(&down, 0)
+		time.Sleep(100 * time.Millisecond)
+	}
+}
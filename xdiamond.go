@@ -1,7 +1,9 @@
 package conf
 
 import (
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
@@ -20,19 +22,34 @@ type xdiamond struct {
 	TCPAddress string `toml:"tcp_address"`
 	//HTTPAddress 配置中心http地址
 	HTTPAddress string `toml:"http_address"`
+	//PollInterval http方式定时拉取配置的间隔，比如 "30s"，数值以秒为单位，不设置时不定时拉取
+	PollInterval interface{} `toml:"poll_interval"`
 }
 
 //初始化配置中心基本配置
-func newXdiamond() *xdiamond {
+func newXdiamond(e *env) (*xdiamond, error) {
 	x := new(xdiamond)
 	xdiamondConfFileName := e.confDir + "comm/xdiamond.toml"
 	_, err := toml.DecodeFile(xdiamondConfFileName, x)
 	if err != nil {
-		Log.Fatal(err)
+		return nil, errors.New("配置中心基础配置" + xdiamondConfFileName + "读取失败:" + err.Error())
 	}
 	x.profile = e.env
 	x.version = "1.0"
-	return x
+	return x, nil
+}
+
+// http方式定时拉取配置的间隔
+func (x *xdiamond) pollInterval() (time.Duration, error) {
+	if x.PollInterval == nil {
+		return 0, nil
+	}
+	r := genResult(x.PollInterval)
+	d, err := r.DurationE()
+	if err != nil {
+		return 0, errors.New("配置中心基础配置poll_interval错误:" + err.Error())
+	}
+	return d, nil
 }
 
 // 提取有效的kv
@@ -1,11 +1,14 @@
 package conf
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"reflect"
+	"time"
 )
 
 const (
@@ -13,33 +16,88 @@ const (
 	uri    = "/clientapi/config"
 )
 
+// 请求超时时间，与定时拉取的间隔无关，拉取间隔更短时下一次拉取等待此次请求结束
+var httpTimeout = 10 * time.Second
+
 type xdiamondHTTP struct {
 	xdiamond
+	client *http.Client
+	// 最近一次拉取的配置数据，定时拉取时用于比对是否有变更
+	last map[string]interface{}
 }
 
 // 实例化配置中心http实例
-func newXdiamondHTTP() *xdiamondHTTP {
-	xdiamond := newXdiamond()
-	return &xdiamondHTTP{xdiamond: *xdiamond}
+func newXdiamondHTTP(e *env) (*xdiamondHTTP, error) {
+	xdiamond, err := newXdiamond(e)
+	if err != nil {
+		return nil, err
+	}
+	_, err = xdiamond.pollInterval()
+	if err != nil {
+		return nil, err
+	}
+	return &xdiamondHTTP{xdiamond: *xdiamond, client: &http.Client{Timeout: httpTimeout}}, nil
 }
 
 // 配置中心配置解析
-func (x xdiamondHTTP) analysisConfig(fileName string) (map[string]interface{}, error) {
+func (x *xdiamondHTTP) Load(fileName string) (map[string]interface{}, error) {
+	return x.load(context.Background(), fileName)
+}
+
+// 拉取配置，ctx 结束时中断请求
+func (x *xdiamondHTTP) load(ctx context.Context, fileName string) (map[string]interface{}, error) {
 	var err error
 	var tmpSlice []interface{}
-	tmpSlice, err = x.synConfigData(x.getObjectAndVersion(fileName))
+	object, version := x.getObjectAndVersion(fileName)
+	tmpSlice, err = x.synConfigData(ctx, object, version)
 	if err != nil {
 		return nil, err
 	}
-	return x.extractKv(tmpSlice), nil
+	x.last = x.extractKv(tmpSlice)
+	return x.last, nil
+}
+
+// 按配置的间隔定时拉取配置，配置内容有变更时回调;拉取失败时只在由成功变为失败时报告一次，避免配置中心不可用期间每次拉取都报告
+func (x *xdiamondHTTP) Watch(ctx context.Context, fileName string, update func(map[string]interface{}, error)) error {
+	interval, err := x.pollInterval()
+	if err != nil || interval <= 0 {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		failing := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				last := x.last
+				data, err := x.load(ctx, fileName)
+				if err != nil {
+					if !failing {
+						failing = true
+						update(nil, err)
+					}
+					continue
+				}
+				failing = false
+				if reflect.DeepEqual(last, data) {
+					continue
+				}
+				update(data, nil)
+			}
+		}
+	}()
+	return nil
 }
 
 // 同步配置中心数据
-func (x *xdiamondHTTP) synConfigData(object string, version string) ([]interface{}, error) {
+func (x *xdiamondHTTP) synConfigData(ctx context.Context, object string, version string) ([]interface{}, error) {
 	var tmp interface{}
 	var err error
 	var data []byte
-	data, err = x.httpPull(object, version)
+	data, err = x.httpPull(ctx, object, version)
 	if err != nil {
 		return nil, err
 	}
@@ -63,11 +121,15 @@ func (x *xdiamondHTTP) synConfigData(object string, version string) ([]interface
 }
 
 // httpPull 从配置中心拉取数据
-func (x *xdiamondHTTP) httpPull(object string, version string) ([]byte, error) {
+func (x *xdiamondHTTP) httpPull(ctx context.Context, object string, version string) ([]byte, error) {
 	var response *http.Response
 	var err error
 	var body []byte
-	response, err = http.Get(x.getFullURL(object, version))
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, x.getFullURL(object, version), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err = x.client.Do(request)
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,7 @@ package conf
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net"
 	"strings"
@@ -43,12 +44,17 @@ const (
 	retryConnInterval = 5 * time.Second
 )
 
+//首次获取配置的超时时间，配置中心接受连接但没有响应时返回错误并从本地备份恢复
+var tcpLoadTimeout = 10 * time.Second
+
 // xdiamondTCP 配置中心tcp同步
 type xdiamondTCP struct {
 	xdiamond
 	client
 	// 暂存 "文件"信息
 	fileName string
+	// 所属配置客户端
+	cli *Client
 }
 
 //请求体
@@ -89,21 +95,35 @@ type client struct {
 }
 
 // 实例化配置中心TCP客户端
-func newXdiamondTCP() *xdiamondTCP {
-	xdiamond := newXdiamond()
+func newXdiamondTCP(cl *Client) (*xdiamondTCP, error) {
+	xdiamond, err := newXdiamond(cl.env)
+	if err != nil {
+		return nil, err
+	}
 	TCPClient := newClient(xdiamond.TCPAddress)
-	return &xdiamondTCP{xdiamond: *xdiamond, client: *TCPClient}
+	return &xdiamondTCP{xdiamond: *xdiamond, client: *TCPClient, cli: cl}, nil
 }
 
 // 获取并解析用户中心配置信息
-func (x *xdiamondTCP) analysisConfig(fileName string) (map[string]interface{}, error) {
+func (x *xdiamondTCP) Load(fileName string) (map[string]interface{}, error) {
 	x.fileName = fileName
 	err := x.start()
 	if err != nil {
 		return nil, err
 	}
 	//阻塞等待返回
-	data := <-x.confChangeChanl
+	timer := time.NewTimer(tcpLoadTimeout)
+	defer timer.Stop()
+	var data []interface{}
+	select {
+	case data = <-x.confChangeChanl:
+	case <-timer.C:
+		x.close()
+		return nil, errors.New("配置中心TCP获取配置超时")
+	case <-x.cli.ctx.Done():
+		x.close()
+		return nil, x.cli.ctx.Err()
+	}
 	//启动go携程消费无缓冲通道
 	go x.synConfigData()
 	return x.extractKv(data), nil
@@ -113,8 +133,13 @@ func (x *xdiamondTCP) analysisConfig(fileName string) (map[string]interface{}, e
 func (x *xdiamondTCP) synConfigData() {
 	for {
 		select {
+		case <-x.cli.ctx.Done():
+			return
 		case data := <-x.confChangeChanl:
-			_ = c.genConfigObject(x.fileName, SourceXdaTCP, x.extractKv(data))
+			_, err := x.cli.genConfigObject(x.fileName, SourceXdaTCP, x.extractKv(data))
+			if err != nil {
+				x.cli.reject(x.fileName, err)
+			}
 		}
 	}
 }
@@ -134,7 +159,7 @@ func newClient(addr string) *client {
 
 // 启动客户端
 func (x *xdiamondTCP) start() error {
-	Log.Info("启动服务...")
+	x.cli.log.Info("启动服务...")
 	if x.conn == nil {
 		conn, err := net.Dial("tcp", x.addr)
 		if err != nil {
@@ -153,10 +178,23 @@ func (x *xdiamondTCP) start() error {
 	return nil
 }
 
+// 关闭客户端，关闭后不再重连
+func (x *xdiamondTCP) close() {
+	if x.stop != nil {
+		x.stop()
+	}
+	if x.conn != nil {
+		_ = x.conn.Close()
+	}
+}
+
 // 重载连接
 func (x *xdiamondTCP) reload() {
+	if x.cli.ctx.Err() != nil {
+		return
+	}
 	if x.stop == nil {
-		Log.Error("服务尚未启动，不能重载...")
+		x.cli.log.Error("服务尚未启动，不能重载...")
 		return
 	}
 	x.loader.Do(func() {
@@ -179,18 +217,18 @@ func (x *xdiamondTCP) load() {
 			case <-time.Tick(retryConnInterval):
 				tries++
 				wg.Done()
-				Log.Info("尝试重连...第", tries, "次...")
+				x.cli.log.Info("尝试重连...第", tries, "次...")
 				conn, err := net.Dial("tcp", x.TCPAddress)
 				if err != nil {
-					Log.Error("连接重载失败...")
+					x.cli.log.Error("连接重载失败...")
 					if tries >= retryConnCount {
-						Log.Error("无法重连请检查网络或配置中心状态 ...")
+						x.cli.log.Error("无法重连请检查网络或配置中心状态 ...")
 						return
 					}
 					continue
 				}
 				x.conn = conn
-				Log.Info("重载连接成功...")
+				x.cli.log.Info("重载连接成功...")
 				_ = x.start()
 				wg.Add(tries - retryConnCount)
 				return
@@ -205,18 +243,18 @@ func (x *xdiamondTCP) handelConn(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			Log.Debug("退出处理协程...")
+			x.cli.log.Debug("退出处理协程...")
 			return
 		default:
 			data, msgType, err := unPacket(x.conn)
 			if err != nil {
 				if err == io.EOF {
-					Log.Error("连接断开:", err)
+					x.cli.log.Error("连接断开:", err)
 					go x.reload()
 					return
 				}
 				if strings.Contains(err.Error(), "use of closed network connection") {
-					Log.Error("连接遭遇非正常的关闭:", err)
+					x.cli.log.Error("连接遭遇非正常的关闭:", err)
 					go x.reload()
 					return
 				}
@@ -242,10 +280,10 @@ func (x *xdiamondTCP) heartCheck(ctx context.Context) {
 		case <-time.Tick(heartInterval):
 			x.sendHeartPacket()
 		case <-x.heartTimmer.C:
-			Log.Debug("心跳超时重载...")
+			x.cli.log.Debug("心跳超时重载...")
 			go x.reload()
 		case <-ctx.Done():
-			Log.Debug("退出心跳计时器...")
+			x.cli.log.Debug("退出心跳计时器...")
 			return
 		}
 	}
@@ -255,12 +293,12 @@ func (x *xdiamondTCP) heartCheck(ctx context.Context) {
 func (x *xdiamondTCP) handelOnewayMessage(data []byte) {
 	res := new(oneway)
 	err := json.Unmarshal(data, res)
-	Log.Debug("Response:", res)
+	x.cli.log.Debug("Response:", res)
 	if err != nil {
-		Log.Error("服务响应json数据解码失败:", err)
+		x.cli.log.Error("服务响应json数据解码失败:", err)
 	}
 	if res.Type == ONEWAY && res.Command == CONFIGCHANGED {
-		Log.Info("配置有变更,准备同步配置数据...")
+		x.cli.log.Info("配置有变更,准备同步配置数据...")
 		x.getConfig()
 	}
 }
@@ -269,34 +307,34 @@ func (x *xdiamondTCP) handelOnewayMessage(data []byte) {
 func (x *xdiamondTCP) handelResponseMessage(data []byte) {
 	res := new(response)
 	err := json.Unmarshal(data, res)
-	Log.Debug("Response:", res)
+	x.cli.log.Debug("Response:", res)
 	if err != nil {
-		Log.Error("服务响应json数据解码失败:", err)
+		x.cli.log.Error("服务响应json数据解码失败:", err)
 	}
 	if !res.Success {
-		Log.Error("服务器响应错误:", res.Error)
+		x.cli.log.Error("服务器响应错误:", res.Error)
 	}
 	switch res.Command {
 	case HEARTBEAT:
-		Log.Debug("收到心跳回包...")
+		x.cli.log.Debug("收到心跳回包...")
 		//重载心跳检测计时器
 		x.heartTimmer.Reset(clientheartInterval)
 	case GETCONFIG:
-		Log.Info("收到配置数据,准备更新...")
+		x.cli.log.Info("收到配置数据,准备更新...")
 		config, ok := res.Result["configs"]
 		if !ok {
-			Log.Error("返回结构错误:", config)
+			x.cli.log.Error("返回结构错误:", config)
 		}
-		Log.Info("更新配置数据...")
+		x.cli.log.Info("更新配置数据...")
 		x.confChangeChanl <- config
 	default:
-		Log.Error("未知的响应类型", res.Command, "消息体:", res)
+		x.cli.log.Error("未知的响应类型", res.Command, "消息体:", res)
 	}
 }
 
 //发送心跳包
 func (x *xdiamondTCP) sendHeartPacket() {
-	Log.Debug("发送心跳数据包....")
+	x.cli.log.Debug("发送心跳数据包....")
 	r := x.newRequest(REQUEST, HEARTBEAT)
 	r.Data = make(auth)
 	x.sendDataPacket(r)
@@ -306,20 +344,20 @@ func (x *xdiamondTCP) sendHeartPacket() {
 
 //发送数据包
 func (x *xdiamondTCP) sendDataPacket(r *request) {
-	Log.Debug("准备发送数据包:", *r)
+	x.cli.log.Debug("准备发送数据包:", *r)
 	data, err := json.Marshal(r)
 	if err != nil {
-		Log.Error("消息结构序列化失败", err)
+		x.cli.log.Error("消息结构序列化失败", err)
 	}
 	_, err = x.conn.Write(packet(r.Type, data))
 	if err != nil {
-		Log.Error("消息发送失败", err)
+		x.cli.log.Error("消息发送失败", err)
 	}
 }
 
 //获取配置
 func (x *xdiamondTCP) getConfig() {
-	Log.Info("更新配置....")
+	x.cli.log.Info("更新配置....")
 	x.sendDataPacket(x.newRequest(REQUEST, GETCONFIG))
 }
 